@@ -0,0 +1,150 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"gopkg.in/telebot.v4"
+	"io"
+	"ocha_server_bot/helper"
+	"strconv"
+	"strings"
+)
+
+// BackupCommandFunc support commands:
+type BackupCommandFunc interface {
+	Backup(c telebot.Context) error
+	Restore(c telebot.Context) error
+}
+
+/*
+/backup  (in a private chat with the bot)
+/restore (as reply to, or caption of, a backup archive; the bot restarts on it)
+*/
+
+// RestoreFunc replaces the data of the running bot with archive and returns
+// the number of restored records.
+type RestoreFunc func(archive []byte) (int, error)
+
+type BackupCommandExec struct {
+	backup   *helper.Backup
+	restore  RestoreFunc
+	owners   helper.Owners
+	langRepo helper.LanguageRepoFunc
+}
+
+func NewBackupCommandExec(backup *helper.Backup, restore RestoreFunc, owners helper.Owners, langRepo helper.LanguageRepoFunc) *BackupCommandExec {
+	return &BackupCommandExec{
+		backup:   backup,
+		restore:  restore,
+		owners:   owners,
+		langRepo: langRepo,
+	}
+}
+
+// Backup sends an archive of every repo, encrypted like the repo files, to
+// an owner in a private chat only, so it never reaches other members.
+func (b *BackupCommandExec) Backup(c telebot.Context) error {
+	if !b.owners.Contains(c.Sender().ID) {
+		return nil
+	}
+	if c.Chat().Type != telebot.ChatPrivate {
+		text, err := helper.Messages[b.langRepo.Context(c)]["backup.private.note"].Execute(map[string]string{
+			"Username": c.Sender().Username,
+		})
+		if err != nil {
+			return err
+		}
+		return c.Send(text)
+	}
+	var buf bytes.Buffer
+	archive, err := b.backup.Export(&buf)
+	if err != nil {
+		return err
+	}
+	records := 0
+	for _, repo := range archive.Repos {
+		records += len(repo.Data)
+	}
+	text, err := helper.Messages[b.langRepo.Context(c)]["backup.note"].Execute(map[string]string{
+		"Username": c.Sender().Username,
+		"Repos":    strings.Join(b.backup.Names(), ", "),
+		"Records":  strconv.Itoa(records),
+		"Time":     archive.Created.Format("2006-01-02 15:04:05"),
+	})
+	if err != nil {
+		return err
+	}
+	return c.Send(&telebot.Document{
+		File:     telebot.FromReader(&buf),
+		FileName: b.backup.FileName(archive.Created),
+		Caption:  text,
+	})
+}
+
+// Restore checks an archive and imports it. The bot stops handling updates
+// while its repos are replaced and starts again on the restored data.
+func (b *BackupCommandExec) Restore(c telebot.Context) error {
+	if !b.owners.Contains(c.Sender().ID) {
+		return nil
+	}
+	lang := b.langRepo.Context(c)
+	var doc *telebot.Document
+	if msg := c.Message(); msg != nil {
+		if msg.Document != nil {
+			doc = msg.Document
+		} else if msg.ReplyTo != nil && msg.ReplyTo.Document != nil {
+			doc = msg.ReplyTo.Document
+		}
+	}
+	if doc == nil {
+		text, err := helper.Messages[lang]["restore.help.note"].Execute(map[string]string{
+			"Username": c.Sender().Username,
+		})
+		if err != nil {
+			return err
+		}
+		return c.Send(text)
+	}
+
+	reader, err := c.Bot().File(&doc.File)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	archive, err := b.backup.Validate(bytes.NewReader(data))
+	if err != nil {
+		return errors.New("restore failed: " + err.Error())
+	}
+	fields := map[string]string{
+		"Username": c.Sender().Username,
+		"Time":     archive.Created.Format("2006-01-02 15:04:05"),
+	}
+	records := 0
+	for _, repo := range archive.Repos {
+		records += len(repo.Data)
+	}
+	fields["Records"] = strconv.Itoa(records)
+	text, err := helper.Messages[lang]["restore.start.note"].Execute(fields)
+	if err != nil {
+		return err
+	}
+	if err = c.Send(text); err != nil {
+		return err
+	}
+
+	// the handlers and repos of this command are replaced by the restart
+	restored, err := b.restore(data)
+	if err != nil {
+		return errors.New("restore failed: " + err.Error())
+	}
+	fields["Records"] = strconv.Itoa(restored)
+	text, err = helper.Messages[lang]["restore.note"].Execute(fields)
+	if err != nil {
+		return err
+	}
+	return c.Send(text)
+}
@@ -0,0 +1,251 @@
+package command
+
+import (
+	"log"
+	"ocha_server_bot/command/mine"
+	"ocha_server_bot/helper"
+	"slices"
+	"strconv"
+	"time"
+
+	"gopkg.in/telebot.v4"
+)
+
+// FameCommandFunc support commands:
+type FameCommandFunc interface {
+	Fame(c telebot.Context) error
+	Winners(c telebot.Context) error
+}
+
+/*
+/mine_fame    [board] [global|chat|topic] [day|week|month]
+/mine_winners (toggle the weekly winners post of the chat or topic)
+*/
+
+// archiveSpec archives the leaderboards of finished periods shortly after
+// midnight, when days, weeks and months end.
+const archiveSpec = "5 0 * * *"
+
+// fameLines is how many archived periods /mine_fame shows.
+const fameLines = 10
+
+// Scheduler runs jobs on cron specs.
+type Scheduler interface {
+	Schedule(spec string, job func()) error
+}
+
+// FameRepo stores the hall of fame, indexed by mine.IndexFame.
+type FameRepo interface {
+	helper.Repo[mine.Fame]
+	helper.Indexer[mine.Fame]
+}
+
+type FameCommandExec struct {
+	bot      *telebot.Bot
+	rank     *helper.KeyedRank[mine.TelegramMineGameScore]
+	fame     FameRepo
+	winners  helper.Repo[mine.WinnersChat]
+	langRepo helper.LanguageRepoFunc
+}
+
+func NewFameCommandExec(
+	bot *telebot.Bot,
+	rank *helper.KeyedRank[mine.TelegramMineGameScore],
+	fame FameRepo,
+	winners helper.Repo[mine.WinnersChat],
+	langRepo helper.LanguageRepoFunc,
+) *FameCommandExec {
+	return &FameCommandExec{
+		bot:      bot,
+		rank:     rank,
+		fame:     fame,
+		winners:  winners,
+		langRepo: langRepo,
+	}
+}
+
+// Start archives the periods that ended while the bot was down and schedules
+// the archiving of the following ones.
+func (f *FameCommandExec) Start(s Scheduler) error {
+	f.Archive(time.Now())
+	return s.Schedule(archiveSpec, func() {
+		f.Archive(time.Now())
+	})
+}
+
+// Archive moves the leaderboards of periods before now into the hall of fame
+// and posts the winners of finished weeks to the chats that asked for them.
+func (f *FameCommandExec) Archive(now time.Time) {
+	weeks := make(map[string]mine.Fame)
+	for _, key := range f.rank.Keys() {
+		period, rank := mine.SplitPeriod(key)
+		w := mine.PeriodWindow(period)
+		if w == mine.WindowAll || period == mine.Period(w, now) {
+			continue
+		}
+		// the rank is only dropped once it is archived
+		items := f.rank.Rank(key).Items()
+		if len(items) == 0 {
+			f.rank.Drop(key)
+			continue
+		}
+		start, end, _ := mine.PeriodSpan(period, now.Location())
+		fame := mine.Fame{
+			Key:     rank,
+			Period:  period,
+			Start:   start,
+			End:     end,
+			Entries: len(items),
+		}
+		for _, item := range items[:min(len(items), mine.FameWinners)] {
+			fame.Winners = append(fame.Winners, item.Item)
+		}
+		if !f.fame.Put(mine.FameID(period, rank), fame) {
+			log.Printf("Archive leaderboard %s failed", key)
+			continue
+		}
+		f.rank.Drop(key)
+		if w == mine.WindowWeek {
+			weeks[rank] = fame
+		}
+	}
+	if len(weeks) > 0 {
+		f.postWinners(weeks)
+	}
+}
+
+// postWinners posts the archived week of every board to the chats and
+// topics that asked for it.
+func (f *FameCommandExec) postWinners(weeks map[string]mine.Fame) {
+	f.winners.Range(func(_ string, sub mine.WinnersChat) bool {
+		scope := mine.ScopeChat
+		if sub.Topic != 0 {
+			scope = mine.ScopeTopic
+		}
+		var (
+			lines string
+			week  mine.Fame
+		)
+		for _, board := range boards(f.rank) {
+			fame, ok := weeks[mine.RankKey(board, scope, sub.Chat, sub.Topic)]
+			if !ok {
+				continue
+			}
+			week = fame
+			lines = lines + fameLine(sub.Locale, boardLabel(sub.Locale, board), fame)
+		}
+		if lines == "" {
+			return true
+		}
+		text, err := helper.Messages[sub.Locale]["mine.winners.week.note"].Execute(map[string]string{
+			"Start": week.Start.Format("2006-01-02"),
+			"End":   week.End.AddDate(0, 0, -1).Format("2006-01-02"),
+			"Lines": lines,
+		})
+		if err == nil {
+			_, err = f.bot.Send(&telebot.Chat{ID: sub.Chat}, text, &telebot.Topic{ThreadID: sub.Topic}, telebot.ModeHTML)
+		}
+		if err != nil {
+			log.Printf("Post weekly winners to Chat(id=%d, topic=%d) failed: %v", sub.Chat, sub.Topic, err)
+		}
+		return true
+	})
+}
+
+// fameLine formats the winners of one archived period.
+func fameLine(lang, title string, fame mine.Fame) string {
+	winners := ""
+	for i, score := range fame.Winners {
+		line, _ := helper.Messages[lang]["mine.fame.winner.note"].Execute(map[string]string{
+			"Place":    strconv.Itoa(i + 1),
+			"Username": score.Username,
+			"Score":    strconv.FormatFloat(score.Score, 'f', 2, 64),
+		})
+		winners = winners + line
+	}
+	text, _ := helper.Messages[lang]["mine.fame.line.note"].Execute(map[string]string{
+		"Title":   title,
+		"Entries": strconv.Itoa(fame.Entries),
+		"Winners": winners,
+	})
+	return text
+}
+
+// Fame shows the winners of the last periods of a leaderboard, the weekly
+// one of the chat by default.
+func (f *FameCommandExec) Fame(c telebot.Context) error {
+	var (
+		l      = f.langRepo.Context(c)
+		chat   = c.Chat().ID
+		topic  = c.Message().ThreadID
+		scopes = mine.Scopes(chat, topic)
+	)
+	board, scope, window, ok := parseRankArgs(c.Args(), scopes, mine.WindowWeek)
+	if !ok || window == mine.WindowAll {
+		return unknownRank(l, c, boards(f.rank))
+	}
+	page, err := f.fame.Query(mine.FameIndexRank, mine.FameRank(window, mine.RankKey(board, scope, chat, topic)), "", 0)
+	if err != nil {
+		return err
+	}
+
+	lines := ""
+	values := page.Values[max(0, len(page.Values)-fameLines):]
+	slices.Reverse(values)
+	for _, fame := range values {
+		title := fame.Start.Format("2006-01-02")
+		if window != mine.WindowDay {
+			title = title + " ~ " + fame.End.AddDate(0, 0, -1).Format("2006-01-02")
+		}
+		lines = lines + fameLine(l, title, fame)
+	}
+	text, err := helper.Messages[l]["mine.fame.res.note"].Execute(map[string]string{
+		"Username": c.Sender().Username,
+		"Board":    rankLabel(l, board, scope, window),
+		"Lines":    lines,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Send(text, telebot.ModeHTML)
+}
+
+// Winners toggles the weekly winners post of the chat, or of the topic when
+// sent in one. Only admins of groups may change it.
+func (f *FameCommandExec) Winners(c telebot.Context) error {
+	var (
+		l     = f.langRepo.Context(c)
+		chat  = c.Chat().ID
+		topic = c.Message().ThreadID
+		note  string
+	)
+	admin, err := helper.IsChatAdmin(c)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(mine.Scopes(chat, topic)) == 1:
+		note = "mine.winners.group.note"
+	case !admin:
+		note = "mine.winners.admin.note"
+	default:
+		id := helper.ID(chat)
+		if topic != 0 {
+			id = helper.TopicID(chat, topic)
+		}
+		if _, ok := f.winners.Get(id); ok {
+			f.winners.Del(id)
+			note = "mine.winners.off.note"
+		} else {
+			f.winners.Put(id, mine.WinnersChat{Chat: chat, Topic: topic, Locale: l, Since: time.Now()})
+			note = "mine.winners.on.note"
+		}
+	}
+	text, err := helper.Messages[l][note].Execute(map[string]string{
+		"Username": c.Sender().Username,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Send(text)
+}
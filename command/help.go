@@ -49,7 +49,7 @@ func (h HelpCommandExec) Help(c telebot.Context) error {
 		"Username": c.Sender().Username,
 		"Version":  helper.Version,
 		"Update":   helper.Update,
-		"BotName":  helper.BotName,
+		"BotName":  helper.Me(c).Username,
 	})
 	if err != nil {
 		return err
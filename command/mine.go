@@ -5,7 +5,10 @@ import (
 	"gopkg.in/telebot.v4"
 	"ocha_server_bot/command/mine"
 	"ocha_server_bot/helper"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -14,6 +17,11 @@ type MineCommandFunc interface {
 	Mine(c telebot.Context) error
 	MineR(c telebot.Context) error
 	MineRank(c telebot.Context) error
+	MineRating(c telebot.Context) error
+	MineBan(c telebot.Context) error
+	MineUnban(c telebot.Context) error
+	MineAudit(c telebot.Context) error
+	ModerateRank(c telebot.Context) error
 	Click(c telebot.Context) error
 	Flag(c telebot.Context) error
 	Change(c telebot.Context) error
@@ -23,6 +31,8 @@ type MineCommandFunc interface {
 
 /*
 /mine [][][]      user topic {length = 4,6}
+/mine_rank [board] [global|chat|topic] [all|day|week|month]
+/mine_rating
 /click  game [][]
 /flag   game [][]
 /back   game
@@ -30,29 +40,117 @@ type MineCommandFunc interface {
 /quit   game
 */
 
+const (
+	// endedRetention is how long a game is kept once it reached mine.End.
+	endedRetention = 24 * time.Hour
+	// idleRetention is how long an unfinished game is kept after its last move.
+	idleRetention = 72 * time.Hour
+)
+
 type MineCommandExec struct {
 	repo     helper.Repo[mine.Serialized]
 	langRepo helper.LanguageRepoFunc
 	id       helper.GenID
 	factory  mine.Factory
 	menu     MenuCommandFunc
-	rank     helper.Ranker[mine.TelegramMineGameScore]
+	rank     *helper.KeyedRank[mine.TelegramMineGameScore]
+	best     helper.Repo[mine.TelegramMineGameScore]
+	subs     helper.Repo[mine.Submission]
+	ratings  helper.Ranker[mine.PlayerRating]
+	audit    AuditRepo
+	auditID  helper.GenID
+	owners   helper.Owners
+	// rateMu serializes the read, update and write of a rating.
+	rateMu sync.Mutex
 }
 
 func NewMineCommandExec(
 	repo helper.Repo[mine.Serialized],
-	rank helper.Ranker[mine.TelegramMineGameScore],
+	rank *helper.KeyedRank[mine.TelegramMineGameScore],
+	best helper.Repo[mine.TelegramMineGameScore],
+	subs helper.Repo[mine.Submission],
+	ratings helper.Ranker[mine.PlayerRating],
+	audit AuditRepo,
+	owners helper.Owners,
 	langRepo helper.LanguageRepoFunc,
 	menu MenuCommandFunc,
 ) *MineCommandExec {
-	return &MineCommandExec{
+	auditID, _ := helper.NewSnowflakeID(0)
+	m := &MineCommandExec{
 		repo:     repo,
 		langRepo: langRepo,
 		factory:  mine.Factory{},
 		id:       helper.NewGenRandomRepoShortID(4, 16, 5, repo),
 		rank:     rank,
+		best:     best,
+		subs:     subs,
+		ratings:  ratings,
+		audit:    audit,
+		auditID:  auditID,
+		owners:   owners,
 		menu:     menu,
 	}
+	// games stored before retention existed never expire on their own
+	expiry, _ := repo.(helper.ExpiryRepo)
+	repo.Range(func(key string, value mine.Serialized) bool {
+		if expiry != nil {
+			if _, ok := expiry.ExpiresAt(key); ok {
+				return true
+			}
+		}
+		m.retain(key, value)
+		return true
+	})
+	m.settle()
+	return m
+}
+
+// settle finishes the submissions left Pending by a crash. A game already on
+// the leaderboard is accepted with the rating its player has now; any other
+// is ranked and rated again, so a crash right after rating rates it twice.
+func (m *MineCommandExec) settle() {
+	pending := make(map[string]mine.Submission)
+	m.subs.Range(func(key string, sub mine.Submission) bool {
+		if sub.Pending {
+			pending[key] = sub
+		}
+		return true
+	})
+	for id, sub := range pending {
+		b := boardRank{rank: m.rank, best: m.best, subs: m.subs, rate: m.rate, board: sub.Board}
+		placed, ok := b.placed(sub)
+		if !ok {
+			b.accept(id, sub)
+			continue
+		}
+		if own, ok := m.ratings.RankOf(mine.RatingOwner(mine.NewPlayerRating(sub.Item.User))); ok {
+			placed.Rating = own.Item.Rating
+		}
+		placed.Pending = false
+		m.subs.Update(id, func(_ mine.Submission, exists bool) (mine.Submission, bool) {
+			return placed, exists
+		})
+	}
+}
+
+// retention returns how much longer game should be kept in the repo.
+func retention(game mine.Serialized) time.Duration {
+	last, period := game.Update, idleRetention
+	if game.Status == mine.End {
+		last, period = game.End, endedRetention
+	}
+	if last.IsZero() {
+		last = game.Create
+	}
+	return time.Until(last.Add(period))
+}
+
+func (m *MineCommandExec) retain(id string, game mine.Serialized) {
+	if ttl := retention(game); ttl > 0 {
+		m.repo.Expire(id, ttl)
+	} else {
+		m.repo.Del(id)
+	}
 }
 
 func (m *MineCommandExec) Mine(c telebot.Context) error {
@@ -106,38 +204,427 @@ func (m *MineCommandExec) Mine(c telebot.Context) error {
 		c)
 }
 
+// MineRank shows a page of a leaderboard, /mine_rank [board]
+// [global|chat|topic] [all|day|week|month]. In groups it defaults to the all
+// time leaderboard of the chat. The buttons below page through it, jump to
+// the page of the sender and switch to the other boards, scopes and windows.
+// Moderators may turn the page into the moderation view, see ModerateRank.
 func (m *MineCommandExec) MineRank(c telebot.Context) error {
-	l := m.langRepo.Context(c)
-	lines := ""
-	for i, rank := range m.rank.Items() {
-		if i > 50 {
-			break
-		}
-		score := rank.Item
-		text, err := helper.Messages[l]["mine.game.rank.line.note"].Execute(map[string]string{
-			"Index":    strconv.Itoa(rank.Index + 1),
+	return m.showRank(c, c.Args())
+}
+
+// showRank shows the page of args: board, scope, window, page and mode.
+func (m *MineCommandExec) showRank(c telebot.Context, args []string) error {
+	var (
+		l      = m.langRepo.Context(c)
+		chat   = c.Chat().ID
+		topic  = c.Message().ThreadID
+		scopes = mine.Scopes(chat, topic)
+	)
+	board, scope, window, ok := parseRankArgs(args, scopes, mine.WindowAll)
+	if !ok {
+		return unknownRank(l, c, boards(m.rank))
+	}
+	mod := len(args) > 4 && args[4] == rankModeMod
+	if mod {
+		allowed, err := m.moderator(c, scope)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return m.respond(c, l, "mine.mod.denied.note", nil, true)
+		}
+	}
+
+	var (
+		// the moderation buttons target this period even after it ends
+		period = mine.Period(window, time.Now())
+		key    = mine.PeriodKey(mine.RankKey(board, scope, chat, topic), period)
+		rank   = m.rank.Rank(key)
+		me     = strconv.FormatInt(c.Sender().ID, 10)
+		pages  = max(1, (rank.Len()+rankPageSize-1)/rankPageSize)
+		page   = 0
+	)
+	own, found := rank.RankOf(me)
+	if len(args) > 3 {
+		if args[3] == rankPageMe && found {
+			page = own.Index / rankPageSize
+		} else if p, err := strconv.Atoi(args[3]); err == nil {
+			page = max(0, min(p, pages-1))
+		}
+	}
+
+	var (
+		lines   string
+		entries []helper.RankItem[mine.TelegramMineGameScore]
+		err     error
+	)
+	rank.Range(page*rankPageSize, func(item helper.RankItem[mine.TelegramMineGameScore]) bool {
+		if item.Index >= (page+1)*rankPageSize {
+			return false
+		}
+		entries = append(entries, item)
+		score := item.Item
+		var text string
+		text, err = helper.Messages[l]["mine.game.rank.line.note"].Execute(map[string]string{
+			"Index":    strconv.Itoa(item.Index + 1),
 			"Width":    strconv.Itoa(score.Width),
 			"Height":   strconv.Itoa(score.Height),
 			"Mines":    strconv.Itoa(score.Mines),
 			"Steps":    strconv.Itoa(score.Steps),
-			"Score":    strconv.FormatFloat(rank.Score, 'f', 2, 64),
+			"Score":    strconv.FormatFloat(item.Score, 'f', 2, 64),
 			"Duration": strconv.FormatInt(score.Duration, 10) + "ms",
 			"Username": score.Username,
 		})
-		if err != nil {
-			return err
+		if found && item.Index == own.Index {
+			text = "<b>" + text + "</b>"
 		}
 		lines = lines + text
+		return err == nil
+	})
+	if err != nil {
+		return err
 	}
 	text, err := helper.Messages[l]["mine.game.rank.res.note"].Execute(map[string]string{
 		"Username":  c.Sender().Username,
+		"Board":     rankLabel(l, board, scope, window),
 		"RankLines": lines,
 		"Update":    time.Now().Format("2006-01-02 15:04:05"),
+		"Page":      strconv.Itoa(page + 1),
+		"Pages":     strconv.Itoa(pages),
 	})
 	if err != nil {
 		return err
 	}
-	return c.Send(text, telebot.ModeHTML)
+
+	reply := &telebot.ReplyMarkup{}
+	var (
+		rows []telebot.Row
+		row  telebot.Row
+	)
+	// paging keeps the moderation view
+	pageArgs := func(page string) []string {
+		if mod {
+			return []string{board, string(scope), string(window), page, rankModeMod}
+		}
+		return []string{board, string(scope), string(window), page}
+	}
+	if page > 0 {
+		row = append(row, reply.Data(helper.Messages[l]["mine.game.rank.prev.button"].String(), "mine_rank", pageArgs(strconv.Itoa(page-1))...))
+	}
+	if found {
+		row = append(row, reply.Data(helper.Messages[l]["mine.game.rank.me.button"].String(), "mine_rank", pageArgs(rankPageMe)...))
+	}
+	if page < pages-1 {
+		row = append(row, reply.Data(helper.Messages[l]["mine.game.rank.next.button"].String(), "mine_rank", pageArgs(strconv.Itoa(page+1))...))
+	}
+	if mod {
+		row = append(row, reply.Data(helper.Messages[l]["mine.game.rank.done.button"].String(), "mine_rank", board, string(scope), string(window), strconv.Itoa(page)))
+	} else {
+		// only moderators are offered the moderation view
+		allowed, err := m.moderator(c, scope)
+		if err != nil {
+			return err
+		}
+		if allowed {
+			row = append(row, reply.Data(helper.Messages[l]["mine.game.rank.mod.button"].String(), "mine_rank", board, string(scope), string(window), strconv.Itoa(page), rankModeMod))
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+		row = nil
+	}
+	if mod {
+		rows = append(rows, m.moderateRows(reply, l, c, board, scope, window, period, page, entries)...)
+	}
+	for _, b := range boards(m.rank) {
+		label := boardLabel(l, b)
+		if b == board {
+			label = "· " + label + " ·"
+		}
+		row = append(row, reply.Data(label, "mine_rank", b, string(scope), string(window)))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	if len(scopes) > 1 {
+		row = nil
+		for _, s := range scopes {
+			label := helper.Messages[l]["mine.game.rank.scope."+string(s)+".button"].String()
+			if s == scope {
+				label = "· " + label + " ·"
+			}
+			row = append(row, reply.Data(label, "mine_rank", board, string(s), string(window)))
+		}
+		rows = append(rows, row)
+	}
+	row = nil
+	for _, w := range mine.Windows {
+		label := helper.Messages[l]["mine.game.rank.window."+string(w)+".button"].String()
+		if w == window {
+			label = "· " + label + " ·"
+		}
+		row = append(row, reply.Data(label, "mine_rank", board, string(scope), string(w)))
+	}
+	rows = append(rows, row)
+	reply.Inline(rows...)
+
+	if c.Callback() != nil {
+		// paging to the page already shown changes nothing
+		err = c.Edit(text, telebot.ModeHTML, reply)
+		if errors.Is(err, telebot.ErrSameMessageContent) || errors.Is(err, telebot.ErrMessageNotModified) {
+			return nil
+		}
+		return err
+	}
+	return c.Send(text, telebot.ModeHTML, reply)
+}
+
+const (
+	// rankPageSize is how many entries a page of /mine_rank shows.
+	rankPageSize = 10
+	// rankPageMe is the page of the sender.
+	rankPageMe = "me"
+	// rankModeMod shows the moderation view of a page.
+	rankModeMod = "mod"
+)
+
+// parseRankArgs parses [board] [scope] [window] of a leaderboard. The board
+// defaults to normal, the scope to the narrowest chat scope of scopes and
+// the window to window.
+func parseRankArgs(args []string, scopes []mine.Scope, window mine.Window) (string, mine.Scope, mine.Window, bool) {
+	var (
+		board = mine.Presets[1].Name
+		scope = scopes[min(1, len(scopes)-1)]
+	)
+	if len(args) > 0 {
+		board = args[0]
+	}
+	if len(args) > 1 {
+		scope = mine.Scope(args[1])
+		if named, ok := scopeNames[args[1]]; ok {
+			scope = named
+		}
+	}
+	if len(args) > 2 {
+		window = mine.Window(args[2])
+		if named, ok := windowNames[args[2]]; ok {
+			window = named
+		}
+	}
+	ok := mine.ValidBoard(board) && slices.Contains(scopes, scope) && slices.Contains(mine.Windows, window)
+	return board, scope, window, ok
+}
+
+func unknownRank(lang string, c telebot.Context, boards []string) error {
+	text, err := helper.Messages[lang]["mine.game.rank.unknown.note"].Execute(map[string]string{
+		"Username": c.Sender().Username,
+		"Board":    strings.Join(c.Args(), " "),
+		"Boards":   strings.Join(boards, ", "),
+	})
+	if err != nil {
+		return err
+	}
+	return c.Send(text)
+}
+
+// scopeNames are the scopes accepted by /mine_rank.
+var scopeNames = map[string]mine.Scope{
+	"global": mine.ScopeGlobal,
+	"chat":   mine.ScopeChat,
+	"topic":  mine.ScopeTopic,
+}
+
+// windowNames are the windows accepted by /mine_rank.
+var windowNames = map[string]mine.Window{
+	"all":   mine.WindowAll,
+	"day":   mine.WindowDay,
+	"week":  mine.WindowWeek,
+	"month": mine.WindowMonth,
+}
+
+// rankLabel names the leaderboard of board in scope and window.
+func rankLabel(lang, board string, scope mine.Scope, window mine.Window) string {
+	return boardLabel(lang, board) +
+		" · " + helper.Messages[lang]["mine.game.rank.scope."+string(scope)+".button"].String() +
+		" · " + helper.Messages[lang]["mine.game.rank.window."+string(window)+".button"].String()
+}
+
+// boardRank records a win on the leaderboards of its board in every scope
+// and window, and reports its place on the global all time one and whether
+// it is a personal best on the board. Every ranked game, won or lost, rates
+// its player. A game is recorded once; adding it again returns the place it
+// was given.
+type boardRank struct {
+	rank  *helper.KeyedRank[mine.TelegramMineGameScore]
+	best  helper.Repo[mine.TelegramMineGameScore]
+	subs  helper.Repo[mine.Submission]
+	rate  func(score mine.TelegramMineGameScore, win bool) helper.Rating
+	board string
+}
+
+func (m *MineCommandExec) boardRank(game mine.Mine) boardRank {
+	return boardRank{
+		rank:  m.rank,
+		best:  m.best,
+		subs:  m.subs,
+		rate:  m.rate,
+		board: mine.Board(game.Width(), game.Height(), game.Mines()),
+	}
+}
+
+func (b boardRank) Items() []helper.RankItem[mine.TelegramMineGameScore] {
+	return b.rank.Rank(b.board).Items()
+}
+
+func (b boardRank) At(index int) (helper.RankItem[mine.TelegramMineGameScore], bool) {
+	return b.rank.Rank(b.board).At(index)
+}
+
+func (b boardRank) Len() int {
+	return b.rank.Rank(b.board).Len()
+}
+
+func (b boardRank) Range(start int, f func(helper.RankItem[mine.TelegramMineGameScore]) bool) {
+	b.rank.Rank(b.board).Range(start, f)
+}
+
+func (b boardRank) RankOf(owner string) (helper.RankItem[mine.TelegramMineGameScore], bool) {
+	return b.rank.Rank(b.board).RankOf(owner)
+}
+
+func (b boardRank) Remove(id string) (helper.RankItem[mine.TelegramMineGameScore], bool) {
+	return b.rank.Rank(b.board).Remove(id)
+}
+
+// Ban bans owner from every leaderboard, not only those of the board.
+func (b boardRank) Ban(owner string) []helper.RankItem[mine.TelegramMineGameScore] {
+	return b.rank.Ban(owner)
+}
+
+func (b boardRank) Add(item mine.TelegramMineGameScore) helper.RankItem[mine.TelegramMineGameScore] {
+	sub := b.submit(item, true)
+	return helper.RankItem[mine.TelegramMineGameScore]{
+		Index: sub.Index,
+		Score: sub.Score,
+		Item:  sub.Item,
+		Best:  sub.Best,
+	}
+}
+
+// submit records the result of a ranked game once and returns the record.
+// The record is only claimed under the lock of the submission; ranking and
+// rating write other repos, so they run after it is released, and a record
+// still Pending shows the place its game already holds.
+func (b boardRank) submit(item mine.TelegramMineGameScore, win bool) mine.Submission {
+	var (
+		id      = mine.SubmissionID(item)
+		claimed = false
+	)
+	sub, _ := b.subs.Update(id, func(old mine.Submission, exists bool) (mine.Submission, bool) {
+		if exists {
+			return old, false
+		}
+		claimed = true
+		return mine.Submission{
+			Board:   b.board,
+			Win:     win,
+			Index:   -1,
+			Score:   item.Score,
+			Item:    item,
+			Pending: true,
+		}, true
+	})
+	if !claimed {
+		if sub.Pending {
+			sub, _ = b.placed(sub)
+		}
+		return sub
+	}
+	// submissions are kept as long as the ended game
+	b.subs.Expire(id, endedRetention)
+	return b.accept(id, sub)
+}
+
+// accept ranks and rates the game of a Pending submission and stores the
+// result. The stored record keeps its ttl and is not written back once it
+// is gone.
+func (b boardRank) accept(id string, sub mine.Submission) mine.Submission {
+	if sub.Win {
+		ranked := b.add(sub.Item)
+		sub.Index, sub.Score, sub.Best, sub.Item = ranked.Index, ranked.Score, ranked.Best, ranked.Item
+	}
+	sub.Rating = b.rate(sub.Item, sub.Win)
+	sub.Pending = false
+	b.subs.Update(id, func(_ mine.Submission, exists bool) (mine.Submission, bool) {
+		return sub, exists
+	})
+	return sub
+}
+
+// placed returns a Pending submission with the place its game holds on the
+// global all time leaderboard, and whether the game is on it.
+func (b boardRank) placed(sub mine.Submission) (mine.Submission, bool) {
+	if !sub.Win {
+		return sub, false
+	}
+	own, ok := b.RankOf(mine.ScoreOwner(sub.Item))
+	if !ok || own.Item != sub.Item {
+		return sub, false
+	}
+	sub.Index, sub.Score = own.Index, own.Score
+	return sub, true
+}
+
+func (b boardRank) add(item mine.TelegramMineGameScore) helper.RankItem[mine.TelegramMineGameScore] {
+	best := false
+	b.best.Update(mine.BestID(item.User, b.board), func(old mine.TelegramMineGameScore, exists bool) (mine.TelegramMineGameScore, bool) {
+		if exists && old.Score >= item.Score {
+			return old, false
+		}
+		best = true
+		return item, true
+	})
+	ranked := b.rank.Add(item, mine.RankKeys(item, time.Now())...)[0]
+	ranked.Best = best
+	return ranked
+}
+
+// boards lists the presets, the random maps and every custom board that has
+// a leaderboard.
+func boards(rank *helper.KeyedRank[mine.TelegramMineGameScore]) []string {
+	names := make([]string, 0, len(mine.Presets)+1)
+	for _, p := range mine.Presets {
+		names = append(names, p.Name)
+	}
+	names = append(names, mine.BoardRandom)
+	var custom []string
+	for _, key := range rank.Keys() {
+		b := mine.KeyBoard(key)
+		if _, ok := mine.CustomBoardSide(b); ok && !slices.Contains(custom, b) {
+			custom = append(custom, b)
+		}
+	}
+	slices.SortFunc(custom, func(a, b string) int {
+		sa, _ := mine.CustomBoardSide(a)
+		sb, _ := mine.CustomBoardSide(b)
+		return sa - sb
+	})
+	return append(names, custom...)
+}
+
+func boardLabel(lang, board string) string {
+	if side, ok := mine.CustomBoardSide(board); ok {
+		text, _ := helper.Messages[lang]["mine.game.rank.custom.button"].Execute(map[string]string{
+			"Side": strconv.Itoa(side),
+		})
+		return text
+	}
+	return helper.Messages[lang]["mine.game.menu."+board+".button"].String()
 }
 
 func (m *MineCommandExec) MineR(c telebot.Context) error {
@@ -222,74 +709,97 @@ func (m *MineCommandExec) mine(width, height, mines, message, topic int, user, c
 		if err != nil {
 			return err
 		}
-		if !m.repo.Put(id, game.Serialize()) {
+		data := game.Serialize()
+		if !m.repo.PutWithTTL(id, data, retention(data)) {
 			return errors.New("put repo failed")
 		}
 		switch game.Infos().Type {
 		case mine.ClassicBottom, mine.Classic:
 			return game.Display(c)
 		case mine.Rank:
-			return game.RankDisplay(c, m.rank)
+			return game.RankDisplay(c, m.boardRank(game))
 		default:
 			return game.Display(c)
 		}
 	})
 }
 
-func (m *MineCommandExec) click(id string, user int64, x, y int, c telebot.Context) error {
-	if data, ok := m.repo.Get(id); ok {
-		game := data.Deserialize()
-		if user != game.UserID() {
-			return nil
+// apply runs move against the stored game atomically, so concurrent
+// callbacks for the same game are applied one after another instead of
+// overwriting each other. It reports false if the game does not exist or
+// does not belong to user.
+func (m *MineCommandExec) apply(id string, user int64, move func(game mine.Mine) (mine.Mine, error)) (mine.Mine, bool, error) {
+	var (
+		game mine.Mine
+		err  error
+	)
+	_, ok := m.repo.Update(id, func(data mine.Serialized, exists bool) (mine.Serialized, bool) {
+		if !exists {
+			return data, false
+		}
+		current := data.Deserialize()
+		if user != current.UserID() {
+			return data, false
+		}
+		game, err = move(current)
+		if err != nil || game == nil {
+			return data, false
 		}
+		return game.Serialize(), true
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if ok {
+		m.retain(id, game.Serialize())
+	}
+	return game, ok, nil
+}
+
+func (m *MineCommandExec) click(id string, user int64, x, y int, c telebot.Context) error {
+	game, ok, err := m.apply(id, user, func(game mine.Mine) (mine.Mine, error) {
 		if game.Status() == mine.UnInit {
 			var err error
 			game, err = m.factory.Init(game.(mine.TelegramMineGame), x, y)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
-		game = game.OnClicked(mine.Position{X: x, Y: y})
-
-		if !m.repo.Put(id, game.Serialize()) {
-			return errors.New("put repo failed")
-		}
-		switch game.Infos().Type {
-		case mine.ClassicBottom, mine.Classic:
-			return game.Display(c)
-		case mine.Rank:
-			return game.RankDisplay(c, m.rank)
-		default:
-			return game.Display(c)
+		return game.OnClicked(mine.Position{X: x, Y: y}), nil
+	})
+	if err != nil || !ok {
+		return err
+	}
+	switch game.Infos().Type {
+	case mine.ClassicBottom, mine.Classic:
+		return game.Display(c)
+	case mine.Rank:
+		ranker := m.boardRank(game)
+		if game.Status() == mine.End && !game.Win() {
+			// lost games only rate their player
+			ranker.submit(game.(mine.TelegramMineGame).Score(), false)
 		}
+		return game.RankDisplay(c, ranker)
+	default:
+		return game.Display(c)
 	}
-	return nil
 }
 
 func (m *MineCommandExec) flag(id string, user int64, x, y int, c telebot.Context) error {
-	if data, ok := m.repo.Get(id); ok {
-		game := data.Deserialize()
-		if user != game.UserID() || game.Status() == mine.UnInit {
-			return nil
-		}
-		game = game.OnFlagged(mine.Position{X: x, Y: y})
-
-		if !m.repo.Put(id, game.Serialize()) {
-			return errors.New("put repo failed")
+	game, ok, err := m.apply(id, user, func(game mine.Mine) (mine.Mine, error) {
+		if game.Status() == mine.UnInit {
+			return nil, nil
 		}
-		return game.Display(c)
+		return game.OnFlagged(mine.Position{X: x, Y: y}), nil
+	})
+	if err != nil || !ok {
+		return err
 	}
-	return nil
+	return game.Display(c)
 }
 
 func (m *MineCommandExec) change(id string, user int64, c telebot.Context) error {
-	if data, ok := m.repo.Get(id); ok {
-		game := data.Deserialize()
-
-		if user != game.UserID() {
-			return nil
-		}
-
+	game, ok, err := m.apply(id, user, func(game mine.Mine) (mine.Mine, error) {
 		info := game.Infos()
 		var button mine.Button
 		if info.Button == mine.BClick {
@@ -298,7 +808,7 @@ func (m *MineCommandExec) change(id string, user int64, c telebot.Context) error
 			button = mine.BClick
 		}
 
-		game = game.OnInfoChanged(mine.Additional{
+		return game.OnInfoChanged(mine.Additional{
 			Type:     info.Type,
 			Button:   button,
 			Locale:   info.Locale,
@@ -306,30 +816,22 @@ func (m *MineCommandExec) change(id string, user int64, c telebot.Context) error
 			Chat:     info.Chat,
 			Message:  info.Message,
 			Username: info.Username,
-		})
-
-		if !m.repo.Put(id, game.Serialize()) {
-			return errors.New("put repo failed")
-		}
-		return game.Display(c)
+		}), nil
+	})
+	if err != nil || !ok {
+		return err
 	}
-	return nil
+	return game.Display(c)
 }
 
 func (m *MineCommandExec) rollback(id string, user int64, c telebot.Context) error {
-	if data, ok := m.repo.Get(id); ok {
-		game := data.Deserialize()
-		if user != game.UserID() {
-			return nil
-		}
-		game = game.OnRollback(1)
-
-		if !m.repo.Put(id, game.Serialize()) {
-			return errors.New("put repo failed")
-		}
-		return game.Display(c)
+	game, ok, err := m.apply(id, user, func(game mine.Mine) (mine.Mine, error) {
+		return game.OnRollback(1), nil
+	})
+	if err != nil || !ok {
+		return err
 	}
-	return nil
+	return game.Display(c)
 }
 
 func (m *MineCommandExec) quit(id string, user int64, c telebot.Context) error {
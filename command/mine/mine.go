@@ -31,6 +31,16 @@ type Mine interface {
 	RankDisplay(c telebot.Context, ranker helper.Ranker[TelegramMineGameScore]) error
 }
 
+func init() {
+	helper.RegisterSchema[Serialized]()
+	helper.RegisterSchema[TelegramMineGameScore]()
+	helper.RegisterSchema[Fame]()
+	helper.RegisterSchema[WinnersChat]()
+	helper.RegisterSchema[Submission]()
+	helper.RegisterSchema[PlayerRating]()
+	helper.RegisterSchema[Audit]()
+}
+
 type Serialized struct {
 	ID        string            `json:"id,omitempty"`
 	User      int64             `json:"user,omitempty"`
@@ -57,6 +67,22 @@ func (s Serialized) Deserialize() Mine {
 	}
 }
 
+// Secondary indexes declared on the game repo by Index
+const (
+	IndexUser   = "user"
+	IndexStatus = "status"
+)
+
+// Index declares the secondary indexes used to look up stored games.
+func Index(repo helper.Indexer[Serialized]) {
+	repo.AddIndex(IndexUser, func(s Serialized) string {
+		return strconv.FormatInt(s.User, 10)
+	})
+	repo.AddIndex(IndexStatus, func(s Serialized) string {
+		return strconv.Itoa(int(s.Status))
+	})
+}
+
 // Position for each Steps
 type Position struct {
 	X int `json:"x,omitempty"`
@@ -94,6 +120,15 @@ const (
 	Boom
 )
 
+// Box.Value bit layout, stored as is in Serialized.Boxes. Changing it needs
+// a Serialized migration.
+const (
+	boxNum     = 0xFF
+	boxFlagged = 0x1000
+	boxMine    = 0x10000
+	boxClicked = 0x100000
+)
+
 // Box is a no Status mine unit
 type Box struct {
 	Value int
@@ -108,37 +143,37 @@ func NumBox(value int) Box {
 }
 
 func MineBox() Box {
-	return NewBox(0x10000)
+	return NewBox(boxMine)
 }
 
 func (b Box) Num() int {
-	return b.Value & 0xFF
+	return b.Value & boxNum
 }
 
 func (b Box) IsFlagged() bool {
-	return b.Value&0x1000 != 0
+	return b.Value&boxFlagged != 0
 }
 
 func (b Box) IsClicked() bool {
-	return b.Value&0x100000 != 0
+	return b.Value&boxClicked != 0
 }
 
 func (b Box) IsMine() bool {
-	return b.Value&0x10000 != 0
+	return b.Value&boxMine != 0
 }
 
 func (b Box) Flagged() Box {
 	if b.IsFlagged() {
-		return Box{Value: b.Value &^ 0x1000}
+		return Box{Value: b.Value &^ boxFlagged}
 	}
-	return Box{Value: b.Value | 0x1000}
+	return Box{Value: b.Value | boxFlagged}
 }
 
 func (b Box) Clicked() Box {
 	if b.IsClicked() {
-		return Box{Value: b.Value &^ 0x100000}
+		return Box{Value: b.Value &^ boxClicked}
 	}
-	return Box{Value: b.Value | 0x100000}
+	return Box{Value: b.Value | boxClicked}
 }
 
 type GameType string
@@ -166,6 +201,9 @@ type Additional struct {
 	Message  int
 }
 
+// ToMap encodes a into Serialized.Infos using the keys type, button, locale,
+// username, topic, chat and message. Renaming a key needs a Serialized
+// migration.
 func (a Additional) ToMap() map[string]string {
 	res := map[string]string{
 		"type":   string(a.Type),
@@ -0,0 +1,63 @@
+package mine
+
+import (
+	"ocha_server_bot/helper"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditAction is a moderation of the leaderboards.
+type AuditAction string
+
+const (
+	// AuditRemove deletes one game from the leaderboards.
+	AuditRemove AuditAction = "remove"
+	// AuditBan deletes the games of a player and keeps them out.
+	AuditBan AuditAction = "ban"
+	// AuditUnban lets a banned player in again.
+	AuditUnban AuditAction = "unban"
+)
+
+// Audit records who moderated the leaderboards, where and what was deleted.
+type Audit struct {
+	Action AuditAction `json:"action,omitempty"`
+	// Actor and ActorName are the user who moderated.
+	Actor     int64  `json:"actor,omitempty"`
+	ActorName string `json:"actor_name,omitempty"`
+	Chat      int64  `json:"chat,omitempty"`
+	Topic     int    `json:"topic,omitempty"`
+	// Owner is the player moderated, see ScoreOwner.
+	Owner    string `json:"owner,omitempty"`
+	Username string `json:"username,omitempty"`
+	// Key is the leaderboard the game was removed from, see RankKey.
+	Key string `json:"key,omitempty"`
+	// Item is the removed game.
+	Item    TelegramMineGameScore `json:"item"`
+	Removed int                   `json:"removed,omitempty"`
+	Time    time.Time             `json:"time,omitempty"`
+}
+
+// AuditIndexChat indexes the audit trail by the chat moderated in. Audit
+// records are keyed by helper.SnowflakeID, so a query returns them in the
+// order they happened.
+const AuditIndexChat = "chat"
+
+// IndexAudit declares the secondary indexes of the audit trail.
+func IndexAudit(repo helper.Indexer[Audit]) {
+	repo.AddIndex(AuditIndexChat, func(a Audit) string {
+		return strconv.FormatInt(a.Chat, 10)
+	})
+}
+
+// KeyChat returns the chat of a chat or topic leaderboard key.
+func KeyChat(key string) (int64, bool) {
+	_, key = SplitPeriod(key)
+	_, scope, ok := strings.Cut(key, scopeSeparator)
+	if !ok {
+		return 0, false
+	}
+	chat, _, _ := strings.Cut(scope, ".")
+	id, err := strconv.ParseInt(chat, 10, 64)
+	return id, err == nil
+}
@@ -0,0 +1,272 @@
+package mine
+
+import (
+	"ocha_server_bot/helper"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Preset is a board offered by the menu, ranked on its own leaderboard.
+type Preset struct {
+	Name   string
+	Width  int
+	Height int
+	Mines  int
+}
+
+var Presets = []Preset{
+	{Name: "easy", Width: 6, Height: 6, Mines: 5},
+	{Name: "normal", Width: 8, Height: 8, Mines: 10},
+	{Name: "hard", Width: 8, Height: 8, Mines: 13},
+	{Name: "nightmare", Width: 8, Height: 8, Mines: 17},
+}
+
+const (
+	// BoardRandom ranks the random maps of the menu, whose sides are 3 to 8.
+	BoardRandom = "random"
+	// boardCustom prefixes the boards of other sizes, bucketed by their
+	// longer side rounded up to a multiple of customStep.
+	boardCustom = "c"
+	customStep  = 4
+	randomMax   = 8
+)
+
+// PresetByName returns the preset called name.
+func PresetByName(name string) (Preset, bool) {
+	for _, p := range Presets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// Board returns the leaderboard of a width × height board with mines.
+func Board(width, height, mines int) string {
+	for _, p := range Presets {
+		if p.Width == width && p.Height == height && p.Mines == mines {
+			return p.Name
+		}
+	}
+	side := max(width, height)
+	if side <= randomMax {
+		return BoardRandom
+	}
+	return boardCustom + strconv.Itoa((side+customStep-1)/customStep*customStep)
+}
+
+// ScoreBoard returns the leaderboard of score.
+func ScoreBoard(score TelegramMineGameScore) string {
+	return Board(score.Width, score.Height, score.Mines)
+}
+
+// ValidBoard reports whether board is a name returned by Board.
+func ValidBoard(board string) bool {
+	if _, ok := PresetByName(board); ok || board == BoardRandom {
+		return true
+	}
+	side, ok := CustomBoardSide(board)
+	return ok && side > randomMax
+}
+
+// CustomBoardSide returns the longest side of the boards ranked on a custom
+// board.
+func CustomBoardSide(board string) (int, bool) {
+	n, ok := strings.CutPrefix(board, boardCustom)
+	if !ok {
+		return 0, false
+	}
+	side, err := strconv.Atoi(n)
+	if err != nil || side%customStep != 0 || strconv.Itoa(side) != n {
+		return 0, false
+	}
+	return side, true
+}
+
+// Scope selects who competes on a leaderboard.
+type Scope string
+
+const (
+	ScopeGlobal Scope = "g"
+	ScopeChat   Scope = "c"
+	ScopeTopic  Scope = "t"
+)
+
+const scopeSeparator = "@"
+
+// Scopes returns the scopes a game played in chat and topic is ranked in,
+// widest first. Group IDs are negative; games in private chats are only
+// ranked globally.
+func Scopes(chat int64, topic int) []Scope {
+	scopes := []Scope{ScopeGlobal}
+	if chat < 0 {
+		scopes = append(scopes, ScopeChat)
+		if topic != 0 {
+			scopes = append(scopes, ScopeTopic)
+		}
+	}
+	return scopes
+}
+
+// RankKey returns the key of the leaderboard of board within scope.
+func RankKey(board string, scope Scope, chat int64, topic int) string {
+	switch scope {
+	case ScopeChat:
+		return board + scopeSeparator + strconv.FormatInt(chat, 10)
+	case ScopeTopic:
+		return board + scopeSeparator + strconv.FormatInt(chat, 10) + "." + strconv.Itoa(topic)
+	}
+	return board
+}
+
+// RankKeys returns the keys of every leaderboard score is recorded on at
+// t, in every window and scope, the global all time one first.
+func RankKeys(score TelegramMineGameScore, t time.Time) []string {
+	board := ScoreBoard(score)
+	scopes := Scopes(score.Chat, score.Topic)
+	keys := make([]string, 0, len(scopes)*len(Windows))
+	for _, w := range Windows {
+		for _, scope := range scopes {
+			keys = append(keys, WindowKey(RankKey(board, scope, score.Chat, score.Topic), w, t))
+		}
+	}
+	return keys
+}
+
+// KeyBoard returns the board of a leaderboard key.
+func KeyBoard(key string) string {
+	_, key = SplitPeriod(key)
+	board, _, _ := strings.Cut(key, scopeSeparator)
+	return board
+}
+
+// Window is the time span a leaderboard covers. Windowed leaderboards are
+// keyed by their period, so a new period starts an empty leaderboard.
+type Window string
+
+const (
+	WindowAll   Window = "a"
+	WindowDay   Window = "d"
+	WindowWeek  Window = "w"
+	WindowMonth Window = "m"
+)
+
+var Windows = []Window{WindowAll, WindowDay, WindowWeek, WindowMonth}
+
+const periodSeparator = ":"
+
+// PeriodStart returns the start of the period of w containing t. Weeks start
+// on Monday.
+func PeriodStart(w Window, t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch w {
+	case WindowWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case WindowMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+// Period names the period of w containing t, e.g. w20261012 for the week
+// starting on Monday 2026-10-12. The all time window has no period.
+func Period(w Window, t time.Time) string {
+	switch w {
+	case WindowDay, WindowWeek:
+		return string(w) + PeriodStart(w, t).Format("20060102")
+	case WindowMonth:
+		return string(w) + t.Format("200601")
+	}
+	return ""
+}
+
+// PeriodWindow returns the window of a period name.
+func PeriodWindow(period string) Window {
+	if period == "" {
+		return WindowAll
+	}
+	return Window(period[:1])
+}
+
+// WindowKey returns the key of the leaderboard key within the period of w
+// containing t.
+func WindowKey(key string, w Window, t time.Time) string {
+	return PeriodKey(key, Period(w, t))
+}
+
+// PeriodKey returns the key of the leaderboard key within period, the key
+// itself for the all time period "".
+func PeriodKey(key, period string) string {
+	if period != "" {
+		return period + periodSeparator + key
+	}
+	return key
+}
+
+// SplitPeriod splits a windowed leaderboard key into its period and the key
+// of the board and scope.
+func SplitPeriod(key string) (period, rest string) {
+	if period, rest, ok := strings.Cut(key, periodSeparator); ok {
+		return period, rest
+	}
+	return "", key
+}
+
+// PeriodSpan returns the start and the end of a period name in loc.
+func PeriodSpan(period string, loc *time.Location) (start, end time.Time, ok bool) {
+	if period == "" {
+		return start, end, false
+	}
+	var err error
+	switch PeriodWindow(period) {
+	case WindowDay:
+		start, err = time.ParseInLocation("20060102", period[1:], loc)
+		end = start.AddDate(0, 0, 1)
+	case WindowWeek:
+		start, err = time.ParseInLocation("20060102", period[1:], loc)
+		end = start.AddDate(0, 0, 7)
+	case WindowMonth:
+		start, err = time.ParseInLocation("200601", period[1:], loc)
+		end = start.AddDate(0, 1, 0)
+	default:
+		return start, end, false
+	}
+	return start, end, err == nil
+}
+
+// ScoreOwner returns the player of score. Scores recorded before the user ID
+// was stored fall back to the username.
+func ScoreOwner(score TelegramMineGameScore) string {
+	if score.User != 0 {
+		return strconv.FormatInt(score.User, 10)
+	}
+	return "@" + score.Username
+}
+
+// BestID returns the key of the personal best of user on board.
+func BestID(user int64, board string) string {
+	return strconv.FormatInt(user, 10) + periodSeparator + board
+}
+
+// SubmissionID returns the key of the submission of score. Game IDs are
+// short and reused once a game is gone, so the key includes when the game
+// was created.
+func SubmissionID(score TelegramMineGameScore) string {
+	return score.Game + "." + strconv.FormatInt(score.Created.UnixMilli(), 36)
+}
+
+// Submission records the result of a ranked game, so the game enters the
+// leaderboards and rates its player once however often it is shown.
+type Submission struct {
+	Board string                `json:"board,omitempty"`
+	Win   bool                  `json:"win,omitempty"`
+	Index int                   `json:"index"`
+	Score float64               `json:"score,omitempty"`
+	Best  bool                  `json:"best,omitempty"`
+	Item  TelegramMineGameScore `json:"item"`
+	// Rating is the rating of the player after the game.
+	Rating helper.Rating `json:"rating"`
+	// Pending is set while the game is ranked and rated.
+	Pending bool `json:"pending,omitempty"`
+}
@@ -191,6 +191,13 @@ func (t TelegramMineGame) RankDisplay(c telebot.Context, ranker helper.Ranker[Te
 		if t.Win() {
 
 			item := ranker.Add(t.Score())
+			rank, best := "-", "mine.game.rank.best.old.note"
+			if item.Index >= 0 {
+				rank = strconv.Itoa(item.Index + 1)
+			}
+			if item.Best {
+				best = "mine.game.rank.best.new.note"
+			}
 			text, err = helper.Messages[info.Locale]["mine.game.rank.win.note"].Execute(map[string]string{
 				"Username": c.Sender().Username,
 				"Width":    strconv.Itoa(t.Width()),
@@ -198,8 +205,10 @@ func (t TelegramMineGame) RankDisplay(c telebot.Context, ranker helper.Ranker[Te
 				"Mines":    strconv.Itoa(t.Mines()),
 				"Seconds":  strconv.FormatFloat(t.Duration().Seconds(), 'f', 3, 64),
 				"Score":    strconv.FormatFloat(item.Score, 'f', 2, 64),
-				"Rank":     strconv.Itoa(item.Index + 1),
-				"BotName":  helper.BotName,
+				"Rank":     rank,
+				"Best":     helper.Messages[info.Locale][best].String(),
+				"BotName":  helper.Me(c).Username,
+				"Board":    Board(t.Width(), t.Height(), t.Mines()),
 			})
 		} else {
 
@@ -209,7 +218,8 @@ func (t TelegramMineGame) RankDisplay(c telebot.Context, ranker helper.Ranker[Te
 				"Height":   strconv.Itoa(t.Height()),
 				"Mines":    strconv.Itoa(t.Mines()),
 				"Seconds":  strconv.FormatFloat(t.Duration().Seconds(), 'f', 3, 64),
-				"BotName":  helper.BotName,
+				"BotName":  helper.Me(c).Username,
+				"Board":    Board(t.Width(), t.Height(), t.Mines()),
 			})
 			buttons = append(buttons, []telebot.InlineButton{
 				{
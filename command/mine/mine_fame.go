@@ -0,0 +1,50 @@
+package mine
+
+import (
+	"ocha_server_bot/helper"
+	"time"
+)
+
+// Fame archives the winners of one leaderboard for a finished period.
+type Fame struct {
+	// Key is the board and scope of the leaderboard, see RankKey.
+	Key     string                  `json:"key,omitempty"`
+	Period  string                  `json:"period,omitempty"`
+	Start   time.Time               `json:"start,omitempty"`
+	End     time.Time               `json:"end,omitempty"`
+	Entries int                     `json:"entries,omitempty"`
+	Winners []TelegramMineGameScore `json:"winners,omitempty"`
+}
+
+// FameWinners is how many of the best entries are archived.
+const FameWinners = 3
+
+// FameIndexRank indexes the archive by window and leaderboard, so the
+// history of one leaderboard is a query ordered by period.
+const FameIndexRank = "rank"
+
+// FameID returns the key of the archive of the leaderboard key in period.
+func FameID(period, key string) string {
+	return period + periodSeparator + key
+}
+
+// FameRank returns the value of FameIndexRank of the leaderboard key in w.
+func FameRank(w Window, key string) string {
+	return string(w) + periodSeparator + key
+}
+
+// IndexFame declares the secondary indexes of the hall of fame.
+func IndexFame(repo helper.Indexer[Fame]) {
+	repo.AddIndex(FameIndexRank, func(f Fame) string {
+		return FameRank(PeriodWindow(f.Period), f.Key)
+	})
+}
+
+// WinnersChat is a chat, or a topic of it, that asked for the weekly winners
+// of its own leaderboards.
+type WinnersChat struct {
+	Chat   int64     `json:"chat,omitempty"`
+	Topic  int       `json:"topic,omitempty"`
+	Locale string    `json:"locale,omitempty"`
+	Since  time.Time `json:"since,omitempty"`
+}
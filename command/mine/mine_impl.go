@@ -9,6 +9,8 @@ type TelegramMineGame struct {
 	info Additional
 }
 type TelegramMineGameScore struct {
+	Game     string  `json:"game,omitempty"`
+	User     int64   `json:"user,omitempty"`
 	Username string  `json:"username,omitempty"`
 	Time     string  `json:"time,omitempty"`
 	Duration int64   `json:"duration,omitempty"`
@@ -17,6 +19,10 @@ type TelegramMineGameScore struct {
 	Mines    int     `json:"mines,omitempty"`
 	Width    int     `json:"width,omitempty"`
 	Height   int     `json:"height,omitempty"`
+	Chat     int64   `json:"chat,omitempty"`
+	Topic    int     `json:"topic,omitempty"`
+	// Created tells apart games that were given the same ID.
+	Created time.Time `json:"created,omitempty"`
 }
 
 func (t TelegramMineGame) Score() TelegramMineGameScore {
@@ -46,6 +52,8 @@ func (t TelegramMineGame) Score() TelegramMineGameScore {
 	score := (difficultyScore * 60) + (normalizedEfficiency * 25) + (normalizedTimeEfficiency * 15)
 
 	return TelegramMineGameScore{
+		Game:     t.ID(),
+		User:     t.UserID(),
 		Username: t.info.Username,
 		Time:     time.Now().Format("2006-01-02 15:04:05"),
 		Duration: duration,
@@ -54,6 +62,9 @@ func (t TelegramMineGame) Score() TelegramMineGameScore {
 		Mines:    mines,
 		Width:    width,
 		Height:   height,
+		Chat:     t.info.Chat,
+		Topic:    t.info.Topic,
+		Created:  t.data.Create,
 	}
 }
 
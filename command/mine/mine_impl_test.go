@@ -5,6 +5,7 @@ import (
 	"github.com/go-playground/assert/v2"
 	"ocha_server_bot/helper"
 	"testing"
+	"time"
 )
 
 func TestMineGameBox(t *testing.T) {
@@ -56,7 +57,7 @@ func TestMineGameClick(t *testing.T) {
 }
 
 func TestSerialize(t *testing.T) {
-	repo := helper.NewFileRepo[Serialized]("", "test")
+	repo := helper.NewFileRepo[Serialized](t.TempDir(), "test")
 	f := Factory{}
 	e, _ := f.Empty("id", 1, Additional{}, 1, 1, 1)
 	defer repo.Stop()
@@ -66,3 +67,61 @@ func TestSerialize(t *testing.T) {
 	fmt.Printf("%v", inter)
 	repo.Sync()
 }
+
+func TestBoard(t *testing.T) {
+	assert.Equal(t, Board(6, 6, 5), "easy")
+	assert.Equal(t, Board(8, 8, 17), "nightmare")
+	assert.Equal(t, Board(8, 8, 9), BoardRandom)
+	assert.Equal(t, Board(3, 7, 4), BoardRandom)
+	assert.Equal(t, Board(9, 5, 10), "c12")
+	assert.Equal(t, Board(16, 16, 40), "c16")
+	for _, b := range []string{"easy", "random", "c12", "c16"} {
+		assert.Equal(t, ValidBoard(b), true)
+	}
+	for _, b := range []string{"", "c8", "c13", "c012", "expert"} {
+		assert.Equal(t, ValidBoard(b), false)
+	}
+}
+
+func TestRankKeys(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	score := TelegramMineGameScore{Width: 8, Height: 8, Mines: 10, Chat: 42}
+	assert.Equal(t, RankKeys(score, now), []string{"normal", "d20261018:normal", "w20261012:normal", "m202610:normal"})
+	score.Chat = -100
+	score.Topic = 7
+	keys := RankKeys(score, now)
+	assert.Equal(t, keys[:3], []string{"normal", "normal@-100", "normal@-100.7"})
+	assert.Equal(t, keys[len(keys)-1], "m202610:normal@-100.7")
+	assert.Equal(t, KeyBoard("w20261012:normal@-100.7"), "normal")
+	assert.Equal(t, KeyBoard("normal@-100.7"), "normal")
+	chat, ok := KeyChat("w20261012:normal@-100.7")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, chat, int64(-100))
+	_, ok = KeyChat("d20261018:normal")
+	assert.Equal(t, ok, false)
+}
+
+func TestSubmissionID(t *testing.T) {
+	first := TelegramMineGameScore{Game: "abcd", Created: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)}
+	again := first
+	again.Created = first.Created.Add(time.Hour)
+	assert.Equal(t, SubmissionID(first), SubmissionID(first))
+	assert.NotEqual(t, SubmissionID(first), SubmissionID(again))
+}
+
+func TestPeriod(t *testing.T) {
+	sunday := time.Date(2026, 10, 18, 23, 59, 0, 0, time.UTC)
+	monday := sunday.Add(time.Minute)
+	assert.Equal(t, Period(WindowWeek, sunday), "w20261012")
+	assert.Equal(t, Period(WindowWeek, monday), "w20261019")
+	assert.Equal(t, Period(WindowDay, monday), "d20261019")
+	assert.Equal(t, Period(WindowMonth, monday), "m202610")
+	assert.Equal(t, Period(WindowAll, monday), "")
+
+	start, end, ok := PeriodSpan("w20261012", time.UTC)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, start, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, end, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+	start, end, _ = PeriodSpan("m202612", time.UTC)
+	assert.Equal(t, end.Sub(start), 31*24*time.Hour)
+}
@@ -0,0 +1,57 @@
+package mine
+
+import (
+	"math"
+	"ocha_server_bot/helper"
+	"strconv"
+	"time"
+)
+
+// PlayerRating is the skill of a player over all ranked games.
+type PlayerRating struct {
+	helper.Rating
+	User     int64  `json:"user,omitempty"`
+	Username string `json:"username,omitempty"`
+	Games    int    `json:"games,omitempty"`
+	Wins     int    `json:"wins,omitempty"`
+}
+
+// boardDeviation is how uncertain the difficulty of a board is.
+const boardDeviation = 50.0
+
+// BoardRating rates the difficulty of a board as an opponent: the normal
+// preset is rated helper.InitialRating, denser boards higher and smaller
+// boards lower.
+func BoardRating(width, height, mines int) float64 {
+	cells := float64(width * height)
+	density := float64(mines) / cells
+	return helper.InitialRating + 3000*(density-10.0/64) + 300*math.Log2(cells/64)
+}
+
+// NewPlayerRating returns the rating of a player without games.
+func NewPlayerRating(user int64) PlayerRating {
+	return PlayerRating{Rating: helper.NewRating(), User: user}
+}
+
+// Play returns the rating after the ranked game of score.
+func (p PlayerRating) Play(score TelegramMineGameScore, win bool, now time.Time) PlayerRating {
+	result := 0.0
+	if win {
+		result = 1
+		p.Wins++
+	}
+	p.Rating = p.Rating.Play(BoardRating(score.Width, score.Height, score.Mines), boardDeviation, result, now)
+	p.Username = score.Username
+	p.Games++
+	return p
+}
+
+// RatingOwner returns the player of a rating, see helper.WithOwner.
+func RatingOwner(p PlayerRating) string {
+	return strconv.FormatInt(p.User, 10)
+}
+
+// RatingScore orders the ratings leaderboard.
+func RatingScore(p PlayerRating) float64 {
+	return p.Rating.Rating
+}
@@ -0,0 +1,344 @@
+package command
+
+import (
+	"log"
+	"ocha_server_bot/command/mine"
+	"ocha_server_bot/helper"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/telebot.v4"
+)
+
+/*
+/mine_ban   <user id> (or as reply to the player)
+/mine_unban <user id> (or as reply to the player)
+/mine_audit
+*/
+
+const (
+	// rankModRemove and rankModBan are the actions of the moderation view.
+	rankModRemove = "rm"
+	rankModBan    = "ban"
+	// auditLines is how many records /mine_audit shows.
+	auditLines = 10
+	// modButtonsPerRow is how many entries share a row of the moderation view.
+	modButtonsPerRow = 5
+)
+
+// AuditRepo stores the audit trail of moderation, indexed by mine.IndexAudit.
+type AuditRepo interface {
+	helper.Repo[mine.Audit]
+	helper.Indexer[mine.Audit]
+}
+
+// moderator reports whether the sender of c may moderate the leaderboards of
+// scope: bot owners all of them, chat admins those of their chat.
+func (m *MineCommandExec) moderator(c telebot.Context, scope mine.Scope) (bool, error) {
+	if m.owners.Contains(c.Sender().ID) {
+		return true, nil
+	}
+	if scope == mine.ScopeGlobal {
+		return false, nil
+	}
+	return helper.IsChatAdmin(c)
+}
+
+// respond answers a callback with key, as an alert if alert is set, and
+// sends it as a message otherwise.
+func (m *MineCommandExec) respond(c telebot.Context, lang, key string, data map[string]string, alert bool) error {
+	text, err := helper.Messages[lang][key].Execute(data)
+	if err != nil {
+		return err
+	}
+	if c.Callback() != nil {
+		return c.Respond(&telebot.CallbackResponse{Text: text, ShowAlert: alert})
+	}
+	return c.Send(text)
+}
+
+// moderateRows returns the buttons removing the entries of a page of period
+// and, for bot owners, banning their players.
+func (m *MineCommandExec) moderateRows(
+	reply *telebot.ReplyMarkup,
+	lang string,
+	c telebot.Context,
+	board string,
+	scope mine.Scope,
+	window mine.Window,
+	period string,
+	page int,
+	entries []helper.RankItem[mine.TelegramMineGameScore],
+) []telebot.Row {
+	actions := []string{rankModRemove}
+	if m.owners.Contains(c.Sender().ID) {
+		actions = append(actions, rankModBan)
+	}
+	// the buttons carry the period rather than the window, so they keep
+	// targeting the leaderboard shown after the window rolls over
+	if period == "" {
+		period = string(window)
+	}
+	var rows []telebot.Row
+	for _, action := range actions {
+		var row telebot.Row
+		for _, item := range entries {
+			label, _ := helper.Messages[lang]["mine.game.rank."+action+".button"].Execute(map[string]string{
+				"Index": strconv.Itoa(item.Index + 1),
+			})
+			row = append(row, reply.Data(label, "mine_mod", board, string(scope), period, strconv.Itoa(page), action, item.ID))
+			if len(row) == modButtonsPerRow {
+				rows = append(rows, row)
+				row = nil
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// ModerateRank handles the buttons of the moderation view: it removes the
+// game of an entry from the leaderboards, or bans its player from all of
+// them, records who did it and shows the page again. The entry is looked up
+// on the leaderboard of the period the button was shown for.
+func (m *MineCommandExec) ModerateRank(c telebot.Context) error {
+	var (
+		l      = m.langRepo.Context(c)
+		args   = c.Args()
+		chat   = c.Chat().ID
+		topic  = c.Message().ThreadID
+		scopes = mine.Scopes(chat, topic)
+		owner  = m.owners.Contains(c.Sender().ID)
+	)
+	if len(args) < 6 {
+		return nil
+	}
+	window, period, ok := parsePeriod(args[2])
+	if !ok {
+		return nil
+	}
+	board, scope, _, ok := parseRankArgs(args[:2], scopes, window)
+	if !ok {
+		return nil
+	}
+	allowed, err := m.moderator(c, scope)
+	if err != nil {
+		return err
+	}
+	if !allowed || args[4] == rankModBan && !owner {
+		return m.respond(c, l, "mine.mod.denied.note", nil, true)
+	}
+
+	var (
+		now  = time.Now()
+		key  = mine.PeriodKey(mine.RankKey(board, scope, chat, topic), period)
+		view = []string{board, string(scope), string(window), args[3], rankModeMod}
+	)
+	item, ok := m.rank.Rank(key).Remove(args[5])
+	if !ok {
+		if err := m.respond(c, l, "mine.mod.gone.note", nil, false); err != nil {
+			return err
+		}
+		return m.showRank(c, view)
+	}
+	audit := mine.Audit{
+		Actor:     c.Sender().ID,
+		ActorName: c.Sender().Username,
+		Chat:      chat,
+		Topic:     topic,
+		Owner:     mine.ScoreOwner(item.Item),
+		Username:  item.Item.Username,
+		Key:       key,
+		Item:      item.Item,
+		Time:      now,
+	}
+	note := "mine.mod.removed.note"
+	switch args[4] {
+	case rankModBan:
+		note = "mine.mod.banned.note"
+		audit.Action = mine.AuditBan
+		audit.Removed = 1 + len(m.rank.Ban(audit.Owner))
+		m.ratings.Ban(audit.Owner)
+	default:
+		// chat admins only reach the leaderboards of their chat
+		only := chat
+		if owner {
+			only = 0
+		}
+		audit.Action = mine.AuditRemove
+		audit.Removed = 1 + m.removeGame(item.Item, only)
+	}
+	m.record(audit)
+	if err := m.respond(c, l, note, map[string]string{
+		"Target":  audit.Username,
+		"Removed": strconv.Itoa(audit.Removed),
+	}, false); err != nil {
+		return err
+	}
+	return m.showRank(c, view)
+}
+
+// parsePeriod parses the period of a moderation button, a period name or the
+// all time window.
+func parsePeriod(arg string) (mine.Window, string, bool) {
+	if mine.Window(arg) == mine.WindowAll {
+		return mine.WindowAll, "", true
+	}
+	_, _, ok := mine.PeriodSpan(arg, time.Local)
+	return mine.PeriodWindow(arg), arg, ok
+}
+
+// removeGame removes score from every leaderboard it is on, or only those
+// of chat unless chat is 0, and returns how many it was removed from.
+func (m *MineCommandExec) removeGame(score mine.TelegramMineGameScore, chat int64) int {
+	var (
+		owner   = mine.ScoreOwner(score)
+		removed = 0
+	)
+	for _, key := range m.rank.Keys() {
+		if c, ok := mine.KeyChat(key); chat != 0 && (!ok || c != chat) {
+			continue
+		}
+		rank := m.rank.Rank(key)
+		// a player has one entry per leaderboard, see helper.WithOwner
+		if own, ok := rank.RankOf(owner); ok && own.Item == score {
+			if _, ok := rank.Remove(own.ID); ok {
+				removed++
+			}
+		}
+	}
+	return removed
+}
+
+// record appends audit to the audit trail.
+func (m *MineCommandExec) record(audit mine.Audit) {
+	id, err := m.auditID.NextID()
+	if err == nil && m.audit.Put(id, audit) {
+		return
+	}
+	log.Printf("Record audit %s of %s by %d failed: %v", audit.Action, audit.Owner, audit.Actor, err)
+}
+
+// MineBan bans a player from every leaderboard and the ratings, and deletes
+// their entries. Only bot owners may ban.
+func (m *MineCommandExec) MineBan(c telebot.Context) error {
+	return m.ban(c, mine.AuditBan)
+}
+
+// MineUnban lets a banned player into the leaderboards again. Their entries
+// and rating are not restored.
+func (m *MineCommandExec) MineUnban(c telebot.Context) error {
+	return m.ban(c, mine.AuditUnban)
+}
+
+func (m *MineCommandExec) ban(c telebot.Context, action mine.AuditAction) error {
+	l := m.langRepo.Context(c)
+	if !m.owners.Contains(c.Sender().ID) {
+		return m.respond(c, l, "mine.mod.denied.note", nil, true)
+	}
+	owner, username, ok := banTarget(c)
+	if !ok {
+		return m.respond(c, l, "mine.mod.usage.note", map[string]string{
+			"Username": c.Sender().Username,
+		}, false)
+	}
+
+	audit := mine.Audit{
+		Action:    action,
+		Actor:     c.Sender().ID,
+		ActorName: c.Sender().Username,
+		Chat:      c.Chat().ID,
+		Topic:     c.Message().ThreadID,
+		Owner:     owner,
+		Username:  username,
+		Time:      time.Now(),
+	}
+	note := "mine.mod.ban.note"
+	if action == mine.AuditUnban {
+		note = "mine.mod.unban.note"
+		if !m.rank.Unban(owner) {
+			note = "mine.mod.notbanned.note"
+		}
+	} else {
+		audit.Removed = len(m.rank.Ban(owner))
+		m.ratings.Ban(owner)
+	}
+	if note != "mine.mod.notbanned.note" {
+		m.record(audit)
+	}
+	return m.respond(c, l, note, map[string]string{
+		"Username": c.Sender().Username,
+		"Target":   username,
+		"Removed":  strconv.Itoa(audit.Removed),
+	}, false)
+}
+
+// banTarget returns the player named by the command in c: the sender of the
+// message replied to, a user ID or the @username of a legacy entry.
+func banTarget(c telebot.Context) (owner, username string, ok bool) {
+	if reply := c.Message().ReplyTo; reply != nil && reply.Sender != nil {
+		return strconv.FormatInt(reply.Sender.ID, 10), reply.Sender.Username, true
+	}
+	args := c.Args()
+	if len(args) == 0 {
+		return "", "", false
+	}
+	if _, err := strconv.ParseInt(args[0], 10, 64); err == nil {
+		return args[0], args[0], true
+	}
+	if name, found := strings.CutPrefix(args[0], "@"); found && name != "" {
+		return args[0], name, true
+	}
+	return "", "", false
+}
+
+// MineAudit shows the last moderation of the leaderboards in the chat to
+// bot owners and chat admins.
+func (m *MineCommandExec) MineAudit(c telebot.Context) error {
+	var (
+		l    = m.langRepo.Context(c)
+		chat = c.Chat().ID
+	)
+	allowed, err := m.moderator(c, mine.ScopeChat)
+	if err != nil {
+		return err
+	}
+	if !allowed || chat > 0 && !m.owners.Contains(c.Sender().ID) {
+		return m.respond(c, l, "mine.mod.denied.note", nil, true)
+	}
+	page, err := m.audit.Query(mine.AuditIndexChat, strconv.FormatInt(chat, 10), "", 0)
+	if err != nil {
+		return err
+	}
+
+	lines := ""
+	values := page.Values[max(0, len(page.Values)-auditLines):]
+	for i := len(values) - 1; i >= 0; i-- {
+		audit := values[i]
+		line, err := helper.Messages[l]["mine.audit."+string(audit.Action)+".note"].Execute(map[string]string{
+			"Time":    audit.Time.Format("2006-01-02 15:04"),
+			"Actor":   audit.ActorName,
+			"Target":  audit.Username,
+			"Removed": strconv.Itoa(audit.Removed),
+		})
+		if err != nil {
+			return err
+		}
+		lines = lines + line
+	}
+	key := "mine.audit.res.note"
+	if lines == "" {
+		key = "mine.audit.none.note"
+	}
+	text, err := helper.Messages[l][key].Execute(map[string]string{
+		"Username": c.Sender().Username,
+		"Lines":    lines,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Send(text, telebot.ModeHTML)
+}
@@ -0,0 +1,77 @@
+package command
+
+import (
+	"ocha_server_bot/command/mine"
+	"ocha_server_bot/helper"
+	"strconv"
+	"time"
+
+	"gopkg.in/telebot.v4"
+)
+
+// ratingLines is how many players /mine_rating lists.
+const ratingLines = 10
+
+// rate updates the rating of the player of a ranked game and returns it.
+func (m *MineCommandExec) rate(score mine.TelegramMineGameScore, win bool) helper.Rating {
+	m.rateMu.Lock()
+	defer m.rateMu.Unlock()
+	player := mine.NewPlayerRating(score.User)
+	if old, ok := m.ratings.RankOf(mine.RatingOwner(player)); ok {
+		player = old.Item
+	}
+	player = player.Play(score, win, time.Now())
+	m.ratings.Add(player)
+	return player.Rating
+}
+
+// MineRating shows the rating of the sender and the best rated players.
+func (m *MineCommandExec) MineRating(c telebot.Context) error {
+	var (
+		l     = m.langRepo.Context(c)
+		now   = time.Now()
+		lines string
+		err   error
+	)
+	m.ratings.Range(0, func(item helper.RankItem[mine.PlayerRating]) bool {
+		if item.Index >= ratingLines {
+			return false
+		}
+		fields := ratingFields(item.Item, now)
+		fields["Index"] = strconv.Itoa(item.Index + 1)
+		var text string
+		text, err = helper.Messages[l]["mine.rating.line.note"].Execute(fields)
+		lines = lines + text
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	key := "mine.rating.none.note"
+	player := mine.NewPlayerRating(c.Sender().ID)
+	if own, ok := m.ratings.RankOf(mine.RatingOwner(player)); ok {
+		key, player = "mine.rating.res.note", own.Item
+	}
+	fields := ratingFields(player, now)
+	fields["Username"] = c.Sender().Username
+	fields["Lines"] = lines
+	text, err := helper.Messages[l][key].Execute(fields)
+	if err != nil {
+		return err
+	}
+	return c.Send(text, telebot.ModeHTML)
+}
+
+// ratingFields formats a rating as of now, with the deviation doubled to
+// give the 95% confidence interval.
+func ratingFields(player mine.PlayerRating, now time.Time) map[string]string {
+	rating := player.Decayed(now)
+	return map[string]string{
+		"Username":  player.Username,
+		"Rating":    strconv.FormatFloat(rating.Rating, 'f', 0, 64),
+		"Deviation": strconv.FormatFloat(2*rating.Deviation, 'f', 0, 64),
+		"Games":     strconv.Itoa(player.Games),
+		"Wins":      strconv.Itoa(player.Wins),
+	}
+}
@@ -1,10 +1,14 @@
 package command
 
 import (
+	"cmp"
 	"gopkg.in/telebot.v4"
+	"maps"
 	"ocha_server_bot/command/mine"
 	"ocha_server_bot/helper"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,15 +17,20 @@ type StatusCommandFunc interface {
 	StatusMine(c telebot.Context) error
 }
 
+// statNamespaces is how many namespaces of a repo /stat lists.
+const statNamespaces = 10
+
 type StatusCommandExec struct {
-	repos []helper.RepoInfo
-	lang  helper.LanguageRepoFunc
+	repos      []helper.RepoInfo
+	namespaces []helper.NamespaceInfo
+	lang       helper.LanguageRepoFunc
 }
 
-func NewStatusCommandExec(repos []helper.RepoInfo, lang helper.LanguageRepoFunc) *StatusCommandExec {
+func NewStatusCommandExec(repos []helper.RepoInfo, namespaces []helper.NamespaceInfo, lang helper.LanguageRepoFunc) *StatusCommandExec {
 	return &StatusCommandExec{
-		repos: repos,
-		lang:  lang,
+		repos:      repos,
+		namespaces: namespaces,
+		lang:       lang,
 	}
 }
 
@@ -44,8 +53,10 @@ func (s *StatusCommandExec) Status(c telebot.Context) error {
 	la := s.lang.Context(c)
 	for _, repo := range s.repos {
 		data := "NaN"
+		var shards []int64
 		if file, ok := repo.(helper.FileRepoInfo); ok {
 			data = strconv.FormatInt(file.DataSize(), 10) + " bytes"
+			shards = file.ShardDataSizes()
 		}
 		r, err := helper.Messages[la]["stat.repo.note"].Execute(map[string]string{
 			"Name":     repo.Name(),
@@ -57,6 +68,65 @@ func (s *StatusCommandExec) Status(c telebot.Context) error {
 			return err
 		}
 		repos = repos + r
+		if len(shards) > 1 {
+			sizes := make([]string, len(shards))
+			for i, size := range shards {
+				sizes[i] = strconv.FormatInt(size, 10)
+			}
+			r, err = helper.Messages[la]["stat.repo.shards.note"].Execute(map[string]string{
+				"Shards": strings.Join(sizes, " / ") + " bytes",
+			})
+			if err != nil {
+				return err
+			}
+			repos = repos + r
+		}
+		if cache, ok := repo.(helper.CacheRepoInfo); ok {
+			hits, misses := cache.CacheStats()
+			r, err = helper.Messages[la]["stat.repo.cache.note"].Execute(map[string]string{
+				"Hits":   strconv.FormatInt(hits, 10),
+				"Misses": strconv.FormatInt(misses, 10),
+			})
+			if err != nil {
+				return err
+			}
+			repos = repos + r
+		}
+		for _, ns := range s.namespaces {
+			if ns.Name() != repo.Name() {
+				continue
+			}
+			// leaderboards claim a namespace per board, scope and period, so
+			// only the largest are listed
+			sizes := ns.NamespaceSizes()
+			names := slices.SortedFunc(maps.Keys(sizes), func(a, b helper.Namespace) int {
+				return cmp.Or(sizes[b]-sizes[a], strings.Compare(string(a), string(b)))
+			})
+			for _, name := range names[:min(len(names), statNamespaces)] {
+				r, err = helper.Messages[la]["stat.repo.namespace.note"].Execute(map[string]string{
+					"Namespace": string(name),
+					"Size":      strconv.Itoa(sizes[name]),
+				})
+				if err != nil {
+					return err
+				}
+				repos = repos + r
+			}
+			if len(names) > statNamespaces {
+				rest := 0
+				for _, name := range names[statNamespaces:] {
+					rest += sizes[name]
+				}
+				r, err = helper.Messages[la]["stat.repo.namespace.more.note"].Execute(map[string]string{
+					"More": strconv.Itoa(len(names) - statNamespaces),
+					"Size": strconv.Itoa(rest),
+				})
+				if err != nil {
+					return err
+				}
+				repos = repos + r
+			}
+		}
 	}
 	active, running, total := s.analysisMineGame()
 	m, err := helper.Messages[la]["stat.game.mine.note"].Execute(map[string]string{
@@ -68,8 +138,8 @@ func (s *StatusCommandExec) Status(c telebot.Context) error {
 		return err
 	}
 	text, err := helper.Messages[la]["stat.all.note"].Execute(map[string]string{
-		"BotID":    strconv.FormatInt(helper.BotID, 10),
-		"BotName":  helper.BotName,
+		"BotID":    strconv.FormatInt(helper.Me(c).ID, 10),
+		"BotName":  helper.Me(c).Username,
 		"Version":  helper.Version,
 		"Update":   helper.Update,
 		"RepoSize": strconv.Itoa(len(s.repos)),
@@ -87,20 +157,35 @@ func (s *StatusCommandExec) analysisMineGame() (active, running, total int) {
 	active = 0
 	running = 0
 	total = 0
+	after := time.Now().Add(-2 * time.Minute)
+	count := func(value mine.Serialized) {
+		if value.Status == mine.Running {
+			running++
+			if value.Update.After(after) {
+				active++
+			}
+		}
+	}
 	for _, repo := range s.repos {
-		if r, ok := repo.(helper.Repo[mine.Serialized]); ok {
-			after := time.Now().Add(-2 * time.Minute)
-			r.Range(func(k string, value mine.Serialized) bool {
-				total++
-				if value.Status == mine.Running {
-					running++
-					if value.Update.After(after) {
-						active++
-					}
+		r, ok := repo.(helper.Repo[mine.Serialized])
+		if !ok {
+			continue
+		}
+		if idx, ok := repo.(helper.Indexer[mine.Serialized]); ok {
+			page, err := idx.Query(mine.IndexStatus, strconv.Itoa(int(mine.Running)), "", 0)
+			if err == nil {
+				total += repo.Size()
+				for _, value := range page.Values {
+					count(value)
 				}
-				return true
-			})
+				continue
+			}
 		}
+		r.Range(func(k string, value mine.Serialized) bool {
+			total++
+			count(value)
+			return true
+		})
 	}
 	return active, running, total
 }
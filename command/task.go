@@ -31,6 +31,20 @@ type Task struct {
 	Create   time.Time `json:"create,omitempty"`
 }
 
+func init() {
+	helper.RegisterSchema[Task]()
+}
+
+// TaskIndexChat indexes tasks by the chat they post to
+const TaskIndexChat = "chat"
+
+// IndexTask declares the secondary indexes used to look up stored tasks.
+func IndexTask(repo helper.Indexer[Task]) {
+	repo.AddIndex(TaskIndexChat, func(t Task) string {
+		return strconv.FormatInt(t.Chat, 10)
+	})
+}
+
 type TaskCommandExec struct {
 	bot      *telebot.Bot
 	repo     helper.Repo[Task]
@@ -47,10 +61,11 @@ func NewTaskCommandExec(
 	langRepo helper.LanguageRepoFunc,
 
 ) *TaskCommandExec {
+	id, _ := helper.NewSnowflakeID(0)
 	return &TaskCommandExec{
 		bot:      bot,
 		repo:     repo,
-		id:       &helper.NanoTimeID{},
+		id:       id,
 		langRepo: langRepo,
 		lock:     sync.Mutex{},
 		tasks:    make(map[string]cron.EntryID),
@@ -98,6 +113,24 @@ func (t *TaskCommandExec) Cron(c telebot.Context) error {
 	return nil
 }
 
+// Start schedules the stored tasks and starts the scheduler of the bot.
+func (t *TaskCommandExec) Start() error {
+	err := t.RecoverAll()
+	t.cron.Start()
+	return err
+}
+
+// Schedule runs job on the cron spec with the scheduler of the tasks.
+func (t *TaskCommandExec) Schedule(spec string, job func()) error {
+	_, err := t.cron.AddFunc(spec, job)
+	return err
+}
+
+// Stop stops the scheduler and waits for running tasks.
+func (t *TaskCommandExec) Stop() {
+	<-t.cron.Stop().Done()
+}
+
 func (t *TaskCommandExec) RecoverAll() error {
 	t.repo.Range(func(key string, value Task) bool {
 		if _, ok := t.tasks[key]; !ok {
@@ -159,7 +192,7 @@ func (t *TaskCommandExec) Remove(c telebot.Context) error {
 func (t *TaskCommandExec) List(c telebot.Context) error {
 	lang := t.langRepo.Context(c)
 	lines := ""
-	t.repo.Range(func(key string, value Task) bool {
+	line := func(value Task) {
 		lines = lines + "Task\n\t-ID " + value.ID + "\n\t-Cron " + value.Cron + "\n\t-Type " + value.Type + "\n\t-Editer " + value.Editer + "\n"
 		if value.Type == "message" {
 			last := 10
@@ -169,8 +202,30 @@ func (t *TaskCommandExec) List(c telebot.Context) error {
 			}
 			lines = lines + "\t-Message " + value.Message[1:last] + ".." + strconv.Itoa(len) + "\n"
 		}
-		return true
-	})
+	}
+	// every task is listed unless the chat is asked for, as in "list chat"
+	args := c.Args()
+	if len(args) < 2 || args[1] != TaskIndexChat {
+		t.repo.Range(func(key string, value Task) bool {
+			line(value)
+			return true
+		})
+	} else if idx, ok := t.repo.(helper.Indexer[Task]); ok {
+		page, err := idx.Query(TaskIndexChat, strconv.FormatInt(c.Chat().ID, 10), "", 0)
+		if err != nil {
+			return err
+		}
+		for _, value := range page.Values {
+			line(value)
+		}
+	} else {
+		t.repo.Range(func(key string, value Task) bool {
+			if value.Chat == c.Chat().ID {
+				line(value)
+			}
+			return true
+		})
+	}
 	msg, err := helper.Messages[lang]["cron.list.note"].Execute(map[string]string{
 		"Username":  c.Sender().Username,
 		"TaskLines": lines,
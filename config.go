@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"ocha_server_bot/helper"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*
+BOT_CONFIG=/etc/ocha/bots.json
+
+{
+	"dir": "/var/lib/ocha",
+	"keys": ["<base64 AES key>", "<previous key>"],
+	"bots": [
+		{"token": "123:abc", "owners": [1001]},
+		{"token": "456:def"}
+	]
+}
+*/
+
+// config lists the bots hosted by this process. Every bot keeps its repos
+// in Dir, told apart by the bot ID in the file names. File repos are
+// encrypted with the first of Keys; the others decrypt files written before
+// a key rotation.
+type config struct {
+	Dir  string      `json:"dir"`
+	Keys []string    `json:"keys"`
+	Bots []botConfig `json:"bots"`
+
+	keyring *helper.Keyring
+}
+
+type botConfig struct {
+	Token  string        `json:"token"`
+	Owners helper.Owners `json:"owners"`
+}
+
+// loadConfig reads the file named by BOT_CONFIG. Without it a single bot is
+// configured by BOT_TOKEN and BOT_OWNERS. The data directory defaults to the
+// home directory, and the keys default to the comma separated REPO_KEYS.
+func loadConfig() (config, error) {
+	var cfg config
+	if fn := os.Getenv("BOT_CONFIG"); fn != "" {
+		raw, err := os.ReadFile(fn)
+		if err != nil {
+			return cfg, err
+		}
+		if err = json.Unmarshal(raw, &cfg); err != nil {
+			return cfg, errors.New("decode config " + fn + " failed: " + err.Error())
+		}
+	} else {
+		cfg.Bots = []botConfig{{
+			Token:  os.Getenv("BOT_TOKEN"),
+			Owners: parseOwners(os.Getenv("BOT_OWNERS")),
+		}}
+	}
+
+	if cfg.Dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Dir = home
+	}
+	keys := strings.Join(cfg.Keys, ",")
+	if keys == "" {
+		keys = os.Getenv("REPO_KEYS")
+	}
+	if keys != "" {
+		keyring, err := helper.ParseKeyring(keys)
+		if err != nil {
+			return cfg, errors.New("encryption keys are invalid: " + err.Error())
+		}
+		cfg.keyring = keyring
+	}
+	if len(cfg.Bots) == 0 {
+		return cfg, errors.New("no bot configured")
+	}
+	ids := make(map[int64]bool, len(cfg.Bots))
+	for _, bc := range cfg.Bots {
+		id, err := botID(bc.Token)
+		if err != nil {
+			return cfg, err
+		}
+		if ids[id] {
+			return cfg, errors.New("bot " + strconv.FormatInt(id, 10) + " is configured twice")
+		}
+		ids[id] = true
+	}
+	return cfg, nil
+}
+
+// botID returns the ID of the bot, which prefixes its token.
+func botID(token string) (int64, error) {
+	id, _, ok := strings.Cut(token, ":")
+	if !ok {
+		return 0, errors.New("bot token is required to locate the repos of the bot")
+	}
+	botID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, errors.New("bot token is malformed")
+	}
+	return botID, nil
+}
+
+// parseOwners parses a comma separated list of user IDs.
+func parseOwners(s string) helper.Owners {
+	var owners helper.Owners
+	for _, field := range strings.Split(s, ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64); err == nil {
+			owners = append(owners, id)
+		}
+	}
+	return owners
+}
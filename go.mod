@@ -2,10 +2,31 @@ module ocha_server_bot
 
 go 1.23.4
 
-require gopkg.in/telebot.v4 v4.0.0-beta.4
+require (
+	github.com/klauspost/compress v1.17.11
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	golang.org/x/sys v0.27.0
+	gopkg.in/telebot.v4 v4.0.0-beta.4
+	modernc.org/sqlite v1.34.5
+)
+
+require (
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	github.com/stretchr/testify v1.9.0 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	golang.org/x/mod v0.17.0 // indirect
+	golang.org/x/sync v0.9.0 // indirect
+	golang.org/x/tools v0.21.1-0.20240508182429-e35e4ccd0d2d // indirect
+	modernc.org/libc v1.55.3 // indirect
+	modernc.org/mathutil v1.6.0 // indirect
+	modernc.org/memory v1.8.0 // indirect
+)
 
 require (
-	github.com/go-playground/assert/v2 v2.2.0 // indirect
+	github.com/go-playground/assert/v2 v2.2.0
 	github.com/robfig/cron/v3 v3.0.1
-	github.com/tmc/langchaingo v0.1.13 // indirect
 )
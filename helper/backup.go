@@ -0,0 +1,316 @@
+package helper
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const archiveFormat = 1
+
+// Archive is the versioned export of every repo registered in a Backup.
+type Archive struct {
+	Format  int                    `json:"format"`
+	BotID   int64                  `json:"bot_id"`
+	Version string                 `json:"version"`
+	Created time.Time              `json:"created"`
+	Repos   map[string]ArchiveRepo `json:"repos"`
+}
+
+// archiveHeader is the first line of an encrypted archive. The rest of the
+// file is the gzip compressed Archive sealed with the keyring key called Key.
+type archiveHeader struct {
+	Format int    `json:"format"`
+	Key    string `json:"key"`
+}
+
+// ArchiveRepo holds the records of one repo. Every record has the
+// SchemaVersion given by Schema. Expires holds when the records with a ttl
+// expire, for repos implementing ExpiryRepo.
+type ArchiveRepo struct {
+	Schema  int                        `json:"schema"`
+	Data    map[string]json.RawMessage `json:"data"`
+	Expires map[string]time.Time       `json:"expires,omitempty"`
+}
+
+// Freezer is implemented by repos that can block their writers, so that
+// several repos can be exported at the same point in time.
+type Freezer interface {
+	Freeze() (unfreeze func())
+}
+
+type backupRepo interface {
+	freeze() func()
+	export() (ArchiveRepo, error)
+	// decode validates an archived repo and returns how to import it.
+	decode(archived ArchiveRepo) (apply func() int, err error)
+}
+
+type typedBackupRepo[T any] struct {
+	repo Repo[T]
+}
+
+func (b typedBackupRepo[T]) freeze() func() {
+	if f, ok := b.repo.(Freezer); ok {
+		return f.Freeze()
+	}
+	return func() {}
+}
+
+func (b typedBackupRepo[T]) export() (ArchiveRepo, error) {
+	var err error
+	archived := ArchiveRepo{
+		Schema: SchemaVersion[T](),
+		Data:   make(map[string]json.RawMessage),
+	}
+	b.repo.Range(func(key string, value T) bool {
+		var raw []byte
+		if raw, err = json.Marshal(value); err != nil {
+			err = fmt.Errorf("key %s: %w", key, err)
+			return false
+		}
+		archived.Data[key] = raw
+		if e, ok := b.repo.(ExpiryRepo); ok {
+			if at, ok := e.ExpiresAt(key); ok {
+				if archived.Expires == nil {
+					archived.Expires = make(map[string]time.Time)
+				}
+				archived.Expires[key] = at
+			}
+		}
+		return true
+	})
+	return archived, err
+}
+
+func (b typedBackupRepo[T]) decode(archived ArchiveRepo) (func() int, error) {
+	values := make(map[string]T, len(archived.Data))
+	for key, raw := range archived.Data {
+		value, err := decodeRecord[T](archived.Schema, raw)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", key, err)
+		}
+		values[key] = value
+	}
+	return func() int {
+		// records that expired since the export are not restored
+		now := time.Now()
+		for key, at := range archived.Expires {
+			if !at.After(now) {
+				delete(values, key)
+			}
+		}
+		var stale []string
+		b.repo.Range(func(key string, _ T) bool {
+			if _, ok := values[key]; !ok {
+				stale = append(stale, key)
+			}
+			return true
+		})
+		for _, key := range stale {
+			b.repo.Del(key)
+		}
+		for key, value := range values {
+			if at, ok := archived.Expires[key]; ok {
+				b.repo.PutWithTTL(key, value, at.Sub(now))
+			} else {
+				b.repo.Put(key, value)
+			}
+		}
+		return len(values)
+	}, nil
+}
+
+// Backup exports and restores a fixed set of named repos as one Archive.
+// With a keyring, archives are encrypted like the repo files, so a backup
+// does not leak what the repos keep encrypted at rest.
+type Backup struct {
+	botID   int64
+	keyring *Keyring
+	names   []string
+	repos   map[string]backupRepo
+}
+
+func NewBackup(botID int64, keyring *Keyring) *Backup {
+	return &Backup{botID: botID, keyring: keyring, repos: make(map[string]backupRepo)}
+}
+
+// AddBackup registers repo under name in b.
+func AddBackup[T any](b *Backup, name string, repo Repo[T]) {
+	if _, ok := b.repos[name]; !ok {
+		b.names = append(b.names, name)
+		sort.Strings(b.names)
+	}
+	b.repos[name] = typedBackupRepo[T]{repo: repo}
+}
+
+// Names returns the names of the registered repos.
+func (b *Backup) Names() []string {
+	return b.names
+}
+
+// FileName returns the default file name of an archive created at t.
+func (b *Backup) FileName(t time.Time) string {
+	fn := "ocha_backup_" + strconv.FormatInt(b.botID, 10) + "_" + t.Format("20060102150405") + ".json.gz"
+	if b.keyring != nil {
+		fn = fn + ".enc"
+	}
+	return fn
+}
+
+// Export writes a gzip compressed Archive of every registered repo to w,
+// encrypted with the current key of the keyring if set. Writers of repos
+// implementing Freezer are blocked until all repos are read.
+func (b *Backup) Export(w io.Writer) (Archive, error) {
+	archive := Archive{
+		Format:  archiveFormat,
+		BotID:   b.botID,
+		Version: Version,
+		Created: time.Now(),
+		Repos:   make(map[string]ArchiveRepo, len(b.names)),
+	}
+
+	for _, name := range b.names {
+		unfreeze := b.repos[name].freeze()
+		defer unfreeze()
+	}
+	for _, name := range b.names {
+		archived, err := b.repos[name].export()
+		if err != nil {
+			return archive, fmt.Errorf("export repo %s: %w", name, err)
+		}
+		archive.Repos[name] = archived
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(archive); err != nil {
+		return archive, err
+	}
+	if err := zw.Close(); err != nil {
+		return archive, err
+	}
+	if b.keyring == nil {
+		_, err := buf.WriteTo(w)
+		return archive, err
+	}
+
+	sealed, err := b.keyring.seal(buf.Bytes())
+	if err != nil {
+		return archive, fmt.Errorf("encrypt archive: %w", err)
+	}
+	header, err := json.Marshal(archiveHeader{Format: archiveFormat, Key: b.keyring.id()})
+	if err != nil {
+		return archive, err
+	}
+	if _, err = w.Write(append(header, '\n')); err != nil {
+		return archive, err
+	}
+	_, err = w.Write(sealed)
+	return archive, err
+}
+
+// Restore reads an archive written by Export for the same bot and replaces
+// the content of each registered repo it contains, keeping the ttl of every
+// record. Every record is validated and migrated before the first repo is
+// touched, so a bad archive leaves all repos unchanged. It returns the number
+// of imported records.
+//
+// Restore writes through the repos, so it cannot freeze them, and views
+// loaded from them, such as ranks and scheduled tasks, are not rebuilt. It
+// must only run while nothing else uses the repos: the bot is stopped for
+// it and started again on the restored repos.
+func (b *Backup) Restore(r io.Reader) (int, error) {
+	var archive Archive
+	applies, err := b.decode(r, &archive)
+	if err != nil {
+		return 0, err
+	}
+	records := 0
+	for _, apply := range applies {
+		records += apply()
+	}
+	return records, nil
+}
+
+// Validate checks an archive like Restore without changing any repo. It
+// returns the archive.
+func (b *Backup) Validate(r io.Reader) (Archive, error) {
+	var archive Archive
+	_, err := b.decode(r, &archive)
+	return archive, err
+}
+
+// decode reads an archive into archive and returns how to import each repo.
+// Encrypted archives are told apart from plain ones by their header line.
+func (b *Backup) decode(r io.Reader, archive *Archive) ([]func() int, error) {
+	br := bufio.NewReader(r)
+	if first, err := br.Peek(1); err == nil && first[0] == '{' {
+		plain, err := b.open(br)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(plain)
+	} else {
+		r = br
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+	defer zr.Close()
+
+	if err = json.NewDecoder(zr).Decode(archive); err != nil {
+		return nil, fmt.Errorf("decode archive: %w", err)
+	}
+	if archive.Format != archiveFormat {
+		return nil, fmt.Errorf("unsupported archive format %d", archive.Format)
+	}
+	if archive.BotID != b.botID {
+		return nil, fmt.Errorf("archive of bot %d cannot be restored into bot %d", archive.BotID, b.botID)
+	}
+	if len(archive.Repos) == 0 {
+		return nil, errors.New("archive contains no repos")
+	}
+
+	applies := make([]func() int, 0, len(archive.Repos))
+	for name, archived := range archive.Repos {
+		repo, ok := b.repos[name]
+		if !ok {
+			return nil, fmt.Errorf("archive contains unknown repo %s", name)
+		}
+		apply, err := repo.decode(archived)
+		if err != nil {
+			return nil, fmt.Errorf("validate repo %s: %w", name, err)
+		}
+		applies = append(applies, apply)
+	}
+	return applies, nil
+}
+
+// open decrypts an encrypted archive.
+func (b *Backup) open(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+	var header archiveHeader
+	if err = json.Unmarshal(line, &header); err != nil {
+		return nil, fmt.Errorf("decode archive header: %w", err)
+	}
+	if header.Format != archiveFormat {
+		return nil, fmt.Errorf("unsupported archive format %d", header.Format)
+	}
+	sealed, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+	return b.keyring.open(header.Key, sealed)
+}
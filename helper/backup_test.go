@@ -0,0 +1,93 @@
+package helper
+
+import (
+	"bytes"
+	"errors"
+	"github.com/go-playground/assert/v2"
+	"testing"
+	"time"
+)
+
+func TestBackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	words := NewFileRepo[string](dir, "words")
+	defer words.Stop()
+	counts := NewMemRepo[int]("counts")
+	defer counts.Stop()
+
+	b := NewBackup(0, nil)
+	AddBackup(b, "words", words)
+	AddBackup(b, "counts", counts)
+
+	words.Put("a", "apple")
+	words.PutWithTTL("t", "tick", time.Hour)
+	counts.Put("a", 1)
+
+	var buf bytes.Buffer
+	archive, err := b.Export(&buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(archive.Repos), 2)
+
+	words.Put("b", "banana")
+	counts.Put("a", 2)
+
+	records, err := b.Restore(bytes.NewReader(buf.Bytes()))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, records, 3)
+	_, ok := words.Get("b")
+	assert.Equal(t, ok, false)
+	// ttls are restored, not dropped
+	at, ok := words.ExpiresAt("t")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, at.Sub(archive.Repos["words"].Expires["t"]).Abs() < time.Second, true)
+	_, ok = words.ExpiresAt("a")
+	assert.Equal(t, ok, false)
+	v, _ := counts.Get("a")
+	assert.Equal(t, v, 1)
+
+	other := NewBackup(0, nil)
+	AddBackup(other, "words", words)
+	_, err = other.Restore(bytes.NewReader(buf.Bytes()))
+	assert.NotEqual(t, err, nil)
+	_, err = b.Restore(bytes.NewReader([]byte("not an archive")))
+	assert.NotEqual(t, err, nil)
+
+	// archives only restore into the bot that exported them
+	foreign := NewBackup(42, nil)
+	AddBackup(foreign, "words", words)
+	AddBackup(foreign, "counts", counts)
+	_, err = foreign.Validate(bytes.NewReader(buf.Bytes()))
+	assert.NotEqual(t, err, nil)
+	_, err = foreign.Restore(bytes.NewReader(buf.Bytes()))
+	assert.NotEqual(t, err, nil)
+}
+
+func TestBackupEncrypted(t *testing.T) {
+	words := NewMemRepo[string]("words")
+	defer words.Stop()
+	key, _ := NewKeyring(bytes.Repeat([]byte{1}, 32))
+	other, _ := NewKeyring(bytes.Repeat([]byte{2}, 32))
+
+	b := NewBackup(0, key)
+	AddBackup(b, "words", words)
+	words.Put("a", "apple")
+
+	var buf bytes.Buffer
+	_, err := b.Export(&buf)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, bytes.Contains(buf.Bytes(), []byte("apple")), false)
+
+	words.Put("a", "avocado")
+	records, err := b.Restore(bytes.NewReader(buf.Bytes()))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, records, 1)
+	v, _ := words.Get("a")
+	assert.Equal(t, v, "apple")
+
+	for k, want := range map[*Keyring]error{nil: ErrKeyMissing, other: ErrKeyWrong} {
+		wrong := NewBackup(0, k)
+		AddBackup(wrong, "words", words)
+		_, err = wrong.Validate(bytes.NewReader(buf.Bytes()))
+		assert.Equal(t, errors.Is(err, want), true)
+	}
+}
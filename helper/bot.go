@@ -0,0 +1,31 @@
+package helper
+
+import "gopkg.in/telebot.v4"
+
+// Me returns the user of the bot handling c, so that handlers shared by
+// several bots in one process name the right one.
+func Me(c telebot.Context) *telebot.User {
+	if bot, ok := c.Bot().(*telebot.Bot); ok && bot.Me != nil {
+		return bot.Me
+	}
+	return &telebot.User{}
+}
+
+// IsChatAdmin reports whether the sender of c may change the settings of the
+// chat: an admin in groups and channels, anyone in private chats.
+func IsChatAdmin(c telebot.Context) (bool, error) {
+	switch c.Chat().Type {
+	case telebot.ChatChannel, telebot.ChatSuperGroup, telebot.ChatGroup:
+		members, err := c.Bot().AdminsOf(c.Chat())
+		if err != nil {
+			return false, err
+		}
+		for _, member := range members {
+			if member.User.ID == c.Sender().ID {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,182 @@
+package helper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes the values a FileRepo writes to disk.
+type Codec interface {
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// Compression wraps the snapshot stream of a FileRepo.
+type Compression interface {
+	Name() string
+	Writer(w io.Writer) (io.WriteCloser, error)
+	Reader(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	JSONCodec    Codec = jsonCodec{}
+	GobCodec     Codec = gobCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+
+	NoCompression   Compression = noCompression{}
+	GzipCompression Compression = gzipCompression{}
+	ZstdCompression Compression = zstdCompression{}
+)
+
+// codecs and compressions by the name recorded in file headers, so files
+// written with another configuration can still be read.
+var (
+	codecs = map[string]Codec{
+		JSONCodec.Name():    JSONCodec,
+		GobCodec.Name():     GobCodec,
+		MsgpackCodec.Name(): MsgpackCodec,
+	}
+	compressions = map[string]Compression{
+		NoCompression.Name():   NoCompression,
+		GzipCompression.Name(): GzipCompression,
+		ZstdCompression.Name(): ZstdCompression,
+	}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// gobCodec is compact but cannot decode into untyped values, so records it
+// wrote with an older schema version cannot be migrated.
+type gobCodec struct{}
+
+func (gobCodec) Name() string {
+	return "gob"
+}
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// msgpackCodec uses the json struct tags, so omitempty keeps applying.
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string {
+	return "msgpack"
+}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.SetOmitEmpty(true)
+	enc.UseCompactInts(true)
+	err := enc.Encode(v)
+	return buf.Bytes(), err
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+type noCompression struct{}
+
+func (noCompression) Name() string {
+	return "none"
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func (noCompression) Writer(w io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+func (noCompression) Reader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(r), nil
+}
+
+type gzipCompression struct{}
+
+func (gzipCompression) Name() string {
+	return "gzip"
+}
+
+func (gzipCompression) Writer(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCompression) Reader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type zstdCompression struct{}
+
+func (zstdCompression) Name() string {
+	return "zstd"
+}
+
+func (zstdCompression) Writer(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+func (zstdCompression) Reader(r io.Reader) (io.ReadCloser, error) {
+	dec, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return dec.IOReadCloser(), nil
+}
+
+// CodecByName returns the codec called name, e.g. "msgpack".
+func CodecByName(name string) (Codec, bool) {
+	c, ok := codecs[name]
+	return c, ok
+}
+
+// CompressionByName returns the compression called name, e.g. "zstd".
+func CompressionByName(name string) (Compression, bool) {
+	c, ok := compressions[name]
+	return c, ok
+}
+
+// decodeUntyped decodes data into v, whose records are typed any, so that
+// records of an older schema version can be re-encoded as JSON and migrated.
+func decodeUntyped(c Codec, data []byte, v any) error {
+	if c == GobCodec {
+		return errors.New("records of an older schema version stored with gob cannot be migrated, restore them from a backup")
+	}
+	return c.Unmarshal(data, v)
+}
@@ -1,10 +1,19 @@
 package helper
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 var (
-	BotID   int64
-	BotName string
 	Version = "v0.2.0 (Go Rewrite)"
 	Update  = time.Now().Format("2006-01-02 15:04:05")
 )
+
+// Owners are the users of a bot that may use maintenance commands such as
+// /backup.
+type Owners []int64
+
+func (o Owners) Contains(user int64) bool {
+	return slices.Contains(o, user)
+}
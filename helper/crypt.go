@@ -0,0 +1,109 @@
+package helper
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrKeyMissing is returned when a repo file is encrypted but no key is
+	// configured.
+	ErrKeyMissing = errors.New("repo file is encrypted but no encryption key is configured")
+	// ErrKeyWrong is returned when no configured key decrypts a repo file.
+	ErrKeyWrong = errors.New("repo file cannot be decrypted with the configured encryption keys")
+)
+
+type cipherKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Keyring encrypts repo files with AES-GCM using its first key, and decrypts
+// files written with any of its keys. Appending the old key after a new one
+// rotates the key: files are re-encrypted with the new key at the next
+// compaction.
+type Keyring struct {
+	keys []cipherKey
+}
+
+// NewKeyring creates a keyring of AES-128, AES-192 or AES-256 keys.
+func NewKeyring(keys ...[]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("keyring needs at least one key")
+	}
+	k := &Keyring{}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(key)
+		k.keys = append(k.keys, cipherKey{id: hex.EncodeToString(sum[:4]), aead: aead})
+	}
+	return k, nil
+}
+
+// ParseKeyring creates a keyring from comma separated base64 encoded keys,
+// the current key first.
+func ParseKeyring(s string) (*Keyring, error) {
+	var keys [][]byte
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(field)
+		if err != nil {
+			return nil, errors.New("encryption key is not base64: " + err.Error())
+		}
+		keys = append(keys, key)
+	}
+	return NewKeyring(keys...)
+}
+
+// id returns the ID of the current key, which is stored in file headers to
+// tell which key decrypts them.
+func (k *Keyring) id() string {
+	return k.keys[0].id
+}
+
+// seal encrypts data with the current key and prepends the nonce.
+func (k *Keyring) seal(data []byte) ([]byte, error) {
+	aead := k.keys[0].aead
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+// open decrypts data sealed with the key called id.
+func (k *Keyring) open(id string, data []byte) ([]byte, error) {
+	if k == nil {
+		return nil, ErrKeyMissing
+	}
+	for _, key := range k.keys {
+		if key.id != id {
+			continue
+		}
+		if len(data) < key.aead.NonceSize() {
+			return nil, ErrKeyWrong
+		}
+		plain, err := key.aead.Open(nil, data[:key.aead.NonceSize()], data[key.aead.NonceSize():], nil)
+		if err != nil {
+			return nil, ErrKeyWrong
+		}
+		return plain, nil
+	}
+	return nil, ErrKeyWrong
+}
@@ -0,0 +1,95 @@
+package helper
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+type expiryEntry struct {
+	key   string
+	at    time.Time
+	index int
+}
+
+type expiryEntries []*expiryEntry
+
+func (h expiryEntries) Len() int           { return len(h) }
+func (h expiryEntries) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+
+func (h expiryEntries) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryEntries) Push(x any) {
+	e := x.(*expiryEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryEntries) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+// expiryHeap orders keys by expiration, so reaping costs O(log n) per
+// expiring key instead of a scan of every key. A key has at most one entry.
+type expiryHeap struct {
+	mu      sync.Mutex
+	entries expiryEntries
+	keys    map[string]*expiryEntry
+}
+
+func newExpiryHeap() *expiryHeap {
+	return &expiryHeap{keys: make(map[string]*expiryEntry)}
+}
+
+// schedule sets the expiration of key to at.
+func (h *expiryHeap) schedule(key string, at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if e, ok := h.keys[key]; ok {
+		e.at = at
+		heap.Fix(&h.entries, e.index)
+		return
+	}
+	e := &expiryEntry{key: key, at: at}
+	heap.Push(&h.entries, e)
+	h.keys[key] = e
+}
+
+func (h *expiryHeap) remove(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if e, ok := h.keys[key]; ok {
+		heap.Remove(&h.entries, e.index)
+		delete(h.keys, key)
+	}
+}
+
+// due removes and returns the keys whose expiration is not after now.
+func (h *expiryHeap) due(now time.Time) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var keys []string
+	for len(h.entries) > 0 && !h.entries[0].at.After(now) {
+		e := heap.Pop(&h.entries).(*expiryEntry)
+		delete(h.keys, e.key)
+		keys = append(keys, e.key)
+	}
+	return keys
+}
+
+func (h *expiryHeap) len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.entries)
+}
@@ -27,8 +27,89 @@ func (n *NanoTimeID) WithID(handle func(string) error) error {
 	return handle(id)
 }
 
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	snowflakeMaxNode      = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSequence  = 1<<snowflakeSequenceBits - 1
+	// base62 digits in ascending byte order, so that encoded IDs sort like
+	// the numbers they encode
+	base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// snowflakeLen digits hold any positive int64
+	snowflakeLen = 11
+)
+
+// snowflakeEpoch is the zero time of SnowflakeID timestamps.
+var snowflakeEpoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// SnowflakeID generates time ordered IDs from a 41 bit millisecond timestamp,
+// a 10 bit node and a 12 bit sequence, encoded as 11 base62 digits. IDs of
+// one generator never collide and sort in creation order; generators of
+// different nodes never share IDs.
+type SnowflakeID struct {
+	mu       sync.Mutex
+	node     int64
+	last     int64
+	sequence int64
+}
+
+// NewSnowflakeID returns the generator of node, which must be in
+// [0, snowflakeMaxNode]; node 0 is always valid.
+func NewSnowflakeID(node int64) (*SnowflakeID, error) {
+	if node < 0 || node > snowflakeMaxNode {
+		return nil, fmt.Errorf("snowflake node %d out of range [0, %d]", node, snowflakeMaxNode)
+	}
+	return &SnowflakeID{node: node}, nil
+}
+
+func (s *SnowflakeID) NextID() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Since(snowflakeEpoch).Milliseconds()
+	if now < 0 {
+		return "", errors.New("system clock is before the snowflake epoch")
+	}
+	// A clock moving backwards or an exhausted sequence continues from the
+	// last timestamp instead of reusing IDs.
+	if now <= s.last {
+		s.sequence = (s.sequence + 1) & snowflakeMaxSequence
+		if s.sequence == 0 {
+			s.last++
+		}
+	} else {
+		s.last = now
+		s.sequence = 0
+	}
+	id := s.last<<(snowflakeNodeBits+snowflakeSequenceBits) | s.node<<snowflakeSequenceBits | s.sequence
+	return encodeBase62(id), nil
+}
+
+func (s *SnowflakeID) WithID(handle func(string) error) error {
+	id, err := s.NextID()
+	if err != nil {
+		return err
+	}
+	return handle(id)
+}
+
+func encodeBase62(n int64) string {
+	b := make([]byte, snowflakeLen)
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = base62[n%62]
+		n /= 62
+	}
+	return string(b)
+}
+
+// idClaimTTL is how long a claimed ID stays taken if its value is never
+// stored.
+const idClaimTTL = time.Minute
+
+// GenRandomRepoShortID generates short random IDs that are not used in repo.
+// It tries one ID of each length from MinLen to MaxLen, then Retry more IDs
+// of MaxLen.
 type GenRandomRepoShortID[T any] struct {
-	locks  sync.Map
 	repo   Repo[T]
 	MinLen int
 	MaxLen int
@@ -37,7 +118,6 @@ type GenRandomRepoShortID[T any] struct {
 
 func NewGenRandomRepoShortID[T any](minLen, maxLen, retry int, repo Repo[T]) *GenRandomRepoShortID[T] {
 	return &GenRandomRepoShortID[T]{
-		locks:  sync.Map{},
 		repo:   repo,
 		MaxLen: maxLen,
 		MinLen: minLen,
@@ -45,34 +125,37 @@ func NewGenRandomRepoShortID[T any](minLen, maxLen, retry int, repo Repo[T]) *Ge
 	}
 }
 
+// NextID reserves an unused ID in the repo, see Reserver, so no other
+// generator of the repo hands it out again. The reservation ends when the
+// caller stores the value of the ID, or after idClaimTTL. Repos that cannot
+// reserve only skip the IDs already stored.
 func (g *GenRandomRepoShortID[T]) NextID() (string, error) {
-	for i := g.MinLen; i <= g.MaxLen; i++ {
-		draft := RandomString(i)
-		if _, ok := g.repo.Get(draft); !ok {
-			return draft, nil
-		}
-	}
-	for i := 0; i < g.Retry; i++ {
-		draft := RandomString(i)
-		if _, ok := g.repo.Get(draft); !ok {
+	for i := 0; i <= g.MaxLen-g.MinLen+g.Retry; i++ {
+		draft := RandomString(min(g.MinLen+i, g.MaxLen))
+		if g.claim(draft) {
 			return draft, nil
 		}
 	}
 	return "", errors.New("ID gen failed")
 }
 
+func (g *GenRandomRepoShortID[T]) claim(draft string) bool {
+	if r, ok := g.repo.(Reserver); ok {
+		return r.Reserve(draft, idClaimTTL)
+	}
+	_, exists := g.repo.Get(draft)
+	return !exists
+}
+
+// WithID claims an unused ID like NextID and calls handle, which is expected
+// to store the ID in the repo. The ID is released if handle fails.
 func (g *GenRandomRepoShortID[T]) WithID(handle func(string) error) error {
 	id, err := g.NextID()
 	if err != nil {
 		return err
 	}
-	var mu *sync.Mutex
-	val, _ := g.locks.LoadOrStore(id, &sync.Mutex{})
-	mu = val.(*sync.Mutex)
-	if mu.TryLock() {
-		defer mu.Unlock()
-		defer g.locks.Delete(id)
-		return handle(id)
+	if err = handle(id); err != nil {
+		g.repo.Del(id)
 	}
-	return errors.New("ID gen failed")
+	return err
 }
@@ -0,0 +1,95 @@
+package helper
+
+import (
+	"errors"
+	"github.com/go-playground/assert/v2"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestSnowflakeID(t *testing.T) {
+	gen, err := NewSnowflakeID(1)
+	assert.Equal(t, err, nil)
+	_, err = NewSnowflakeID(snowflakeMaxNode + 1)
+	assert.NotEqual(t, err, nil)
+
+	ids := make([]string, 10000)
+	for i := range ids {
+		ids[i], err = gen.NextID()
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(ids[i]), snowflakeLen)
+	}
+	assert.Equal(t, sort.StringsAreSorted(ids), true)
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		assert.Equal(t, seen[id], false)
+		seen[id] = true
+	}
+
+	// a clock moving backwards keeps IDs increasing
+	gen.last += 1000
+	id, _ := gen.NextID()
+	assert.Equal(t, id > ids[len(ids)-1], true)
+}
+
+func TestGenRandomRepoShortIDWithID(t *testing.T) {
+	repo := NewMemRepo[int]("test")
+	defer repo.Stop()
+	// two letter IDs collide often among 1000 draws
+	gen := NewGenRandomRepoShortID(1, 2, 100, repo)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		handled int
+	)
+	for i := 0; i < 1000; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = gen.WithID(func(id string) error {
+				repo.Put(id, 1)
+				mu.Lock()
+				handled++
+				mu.Unlock()
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	// no ID was handed out twice
+	assert.Equal(t, repo.Size(), handled)
+}
+
+func TestGenRandomRepoShortIDClaim(t *testing.T) {
+	repo := NewMemRepo[int]("test")
+	defer repo.Stop()
+	// generators of two processes sharing the repo
+	a := NewGenRandomRepoShortID(1, 1, 200, repo)
+	b := NewGenRandomRepoShortID(1, 1, 200, repo)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		for _, gen := range []*GenRandomRepoShortID[int]{a, b} {
+			id, err := gen.NextID()
+			assert.Equal(t, err, nil)
+			assert.Equal(t, seen[id], false)
+			seen[id] = true
+		}
+	}
+
+	// a reserved ID holds no value until it is stored
+	assert.Equal(t, repo.Size(), 0)
+
+	// a failed handle releases its ID
+	var claimed string
+	err := a.WithID(func(id string) error {
+		claimed = id
+		return errors.New("failed")
+	})
+	assert.NotEqual(t, err, nil)
+	_, ok := repo.Get(claimed)
+	assert.Equal(t, ok, false)
+}
@@ -0,0 +1,139 @@
+package helper
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// IndexFunc extracts the value a record is indexed under.
+type IndexFunc[T any] func(value T) string
+
+// Page is one page of a Query result. Next is the cursor of the following
+// page and is empty once the result is exhausted.
+type Page[T any] struct {
+	Keys   []string
+	Values []T
+	Next   string
+}
+
+// Indexer is implemented by repos that maintain secondary indexes.
+type Indexer[T any] interface {
+	// AddIndex declares an index and fills it from the records already stored.
+	AddIndex(name string, f IndexFunc[T])
+	// Query returns up to limit records whose index equals value, ordered by
+	// key and starting after cursor. A limit <= 0 returns every match.
+	Query(index, value, cursor string, limit int) (Page[T], error)
+}
+
+var ErrIndexNotFound = errors.New("index not found")
+
+type indexDef[T any] struct {
+	f      IndexFunc[T]
+	values map[string]map[string]struct{}
+	keys   map[string]string
+}
+
+// repoIndex keeps the secondary indexes of a repo in memory. Repos call put
+// and del while holding the lock of the key being changed.
+type repoIndex[T any] struct {
+	mu   sync.RWMutex
+	defs map[string]*indexDef[T]
+}
+
+func (x *repoIndex[T]) add(name string, f IndexFunc[T], all func(func(key string, value T) bool)) {
+	def := &indexDef[T]{
+		f:      f,
+		values: make(map[string]map[string]struct{}),
+		keys:   make(map[string]string),
+	}
+	x.mu.Lock()
+	if x.defs == nil {
+		x.defs = make(map[string]*indexDef[T])
+	}
+	x.defs[name] = def
+	x.mu.Unlock()
+
+	// keys written while filling are already indexed with their newer value
+	all(func(key string, value T) bool {
+		x.mu.Lock()
+		if _, ok := def.keys[key]; !ok {
+			def.put(key, value)
+		}
+		x.mu.Unlock()
+		return true
+	})
+}
+
+func (d *indexDef[T]) put(key string, value T) {
+	d.del(key)
+	v := d.f(value)
+	set, ok := d.values[v]
+	if !ok {
+		set = make(map[string]struct{})
+		d.values[v] = set
+	}
+	set[key] = struct{}{}
+	d.keys[key] = v
+}
+
+func (d *indexDef[T]) del(key string) {
+	v, ok := d.keys[key]
+	if !ok {
+		return
+	}
+	delete(d.keys, key)
+	if set := d.values[v]; set != nil {
+		delete(set, key)
+		if len(set) == 0 {
+			delete(d.values, v)
+		}
+	}
+}
+
+func (x *repoIndex[T]) put(key string, value T) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, def := range x.defs {
+		def.put(key, value)
+	}
+}
+
+func (x *repoIndex[T]) del(key string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, def := range x.defs {
+		def.del(key)
+	}
+}
+
+func (x *repoIndex[T]) query(index, value, cursor string, limit int, get func(key string) (T, bool)) (Page[T], error) {
+	x.mu.RLock()
+	def, ok := x.defs[index]
+	if !ok {
+		x.mu.RUnlock()
+		return Page[T]{}, ErrIndexNotFound
+	}
+	keys := make([]string, 0, len(def.values[value]))
+	for key := range def.values[value] {
+		if key > cursor {
+			keys = append(keys, key)
+		}
+	}
+	x.mu.RUnlock()
+
+	sort.Strings(keys)
+	var page Page[T]
+	for i, key := range keys {
+		if limit > 0 && len(page.Keys) == limit {
+			page.Next = keys[i-1]
+			break
+		}
+		// the record may have expired since it was indexed
+		if v, ok := get(key); ok {
+			page.Keys = append(page.Keys, key)
+			page.Values = append(page.Values, v)
+		}
+	}
+	return page, nil
+}
@@ -3,7 +3,6 @@ package helper
 import (
 	"errors"
 	"gopkg.in/telebot.v4"
-	"strconv"
 )
 
 type LanguageRepoFunc interface {
@@ -14,15 +13,25 @@ type LanguageRepoFunc interface {
 }
 
 type LanguageRepo struct {
-	repo Repo[string]
+	namespaces *Namespaces[string]
+	users      *SubRepo[string]
+	chats      *SubRepo[string]
 }
 
 func NewLanguageRepo(repo Repo[string]) *LanguageRepo {
+	namespaces := NewNamespaces(repo)
 	return &LanguageRepo{
-		repo: repo,
+		namespaces: namespaces,
+		users:      namespaces.Sub(NamespaceUser),
+		chats:      namespaces.Sub(NamespaceChat),
 	}
 }
 
+// Namespaces returns the keyspaces of the language repo.
+func (r LanguageRepo) Namespaces() NamespaceInfo {
+	return r.namespaces
+}
+
 func (r LanguageRepo) Lang(lang string) string {
 	switch lang {
 	case "en":
@@ -37,66 +46,54 @@ func (r LanguageRepo) Lang(lang string) string {
 }
 
 func (r LanguageRepo) Context(c telebot.Context) string {
-	user := strconv.FormatInt(c.Sender().ID, 10)
-	if lang, ok := r.repo.Get("us_" + user); ok {
+	if lang, ok := r.users.Get(ID(c.Sender().ID)); ok {
 		return lang
 	}
-	chat := strconv.FormatInt(c.Chat().ID, 10)
-	if lang, ok := r.repo.Get("ch_" + chat); ok {
+	if lang, ok := r.chats.Get(ID(c.Chat().ID)); ok {
 		return lang
 	}
 	return r.Lang(c.Sender().LanguageCode)
 }
 
 func (r LanguageRepo) SetChatLanguageIfAdminByContext(c telebot.Context, lang string) error {
-	switch c.Chat().Type {
-	case telebot.ChatChannel, telebot.ChatSuperGroup, telebot.ChatGroup:
-		members, err := c.Bot().AdminsOf(c.Chat())
-		if err != nil {
-			return err
-		}
-		admin := false
-		for _, member := range members {
-			if member.User.ID == c.Sender().ID {
-				admin = true
-				break
-			}
-		}
-		if !admin {
-			return errors.New("only admin can set chat language")
-		}
+	admin, err := IsChatAdmin(c)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return errors.New("only admin can set chat language")
 	}
-	if !r.setChatLanguage(strconv.FormatInt(c.Chat().ID, 10), r.Lang(lang)) {
+	if !r.setChatLanguage(ID(c.Chat().ID), r.Lang(lang)) {
 		return errors.New("admin chat language set failed")
 	}
 	return nil
 }
 
 func (r LanguageRepo) SetUserLanguageByContext(c telebot.Context, lang string) error {
-	if !r.setUserLanguage(strconv.FormatInt(c.Sender().ID, 10), r.Lang(lang)) {
+	if !r.setUserLanguage(ID(c.Sender().ID), r.Lang(lang)) {
 		return errors.New("user language set failed")
 	}
 	return nil
 }
 
 func (r LanguageRepo) userLanguage(user string) string {
-	if lang, ok := r.repo.Get("us_" + user); ok {
+	if lang, ok := r.users.Get(user); ok {
 		return lang
 	}
 	return "en"
 }
 
 func (r LanguageRepo) setUserLanguage(user string, lang string) bool {
-	return r.repo.Put("us_"+user, lang)
+	return r.users.Put(user, lang)
 }
 
 func (r LanguageRepo) chatLanguage(chat string) string {
-	if lang, ok := r.repo.Get("ch_" + chat); ok {
+	if lang, ok := r.chats.Get(chat); ok {
 		return lang
 	}
 	return "en"
 }
 
 func (r LanguageRepo) setChatLanguage(chat string, lang string) bool {
-	return r.repo.Put("ch_"+chat, lang)
+	return r.chats.Put(chat, lang)
 }
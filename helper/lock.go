@@ -0,0 +1,43 @@
+package helper
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const lockFile = ".ocha.lock"
+
+// ErrDirLocked is returned by LockDir when another process holds the lock.
+var ErrDirLocked = errors.New("data directory is used by another process")
+
+// LockDir takes an exclusive lock on the data directory dir, so that two
+// processes never write the same repos. The lock is released by unlock or
+// when the process exits.
+func LockDir(dir string) (unlock func() error, err error) {
+	fn := filepath.Join(dir, lockFile)
+	file, err := os.OpenFile(fn, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err = lockFileExclusive(file); err != nil {
+		_ = file.Close()
+		if errors.Is(err, ErrDirLocked) {
+			owner, _ := os.ReadFile(fn)
+			return nil, errors.New(ErrDirLocked.Error() + " (pid " + string(owner) + "): " + fn)
+		}
+		return nil, err
+	}
+	if err = file.Truncate(0); err == nil {
+		_, err = file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return func() error {
+		_ = file.Truncate(0)
+		return errors.Join(unlockFile(file), file.Close())
+	}, nil
+}
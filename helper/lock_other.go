@@ -0,0 +1,22 @@
+//go:build !unix && !windows
+
+package helper
+
+import "os"
+
+// Platforms without file locks only guard against a second LockDir in the
+// same process.
+var lockedFiles = make(map[string]bool)
+
+func lockFileExclusive(file *os.File) error {
+	if lockedFiles[file.Name()] {
+		return ErrDirLocked
+	}
+	lockedFiles[file.Name()] = true
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	delete(lockedFiles, file.Name())
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package helper
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+func lockFileExclusive(file *os.File) error {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrDirLocked
+	}
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -30,6 +30,10 @@ var templates = map[string]map[string]string{
 	"en": {
 		"stat.all.note":                   "Stat report:\n<blockquote expandable>Bot:\nID: {{.BotID}}\nName: {{.BotName}}\nVersion: {{.Version}}\nUpdate: {{.Update}}\n\nRepos:\nsize: {{.RepoSize}}\n{{.Repos}}\n{{.Mine}}\n\nAnalysis:\nTime: {{.Now}}</blockquote>",
 		"stat.repo.note":                  "Repo: {{ .Name }}\n\t| type: {{ .Type }}\n\t| size: {{ .DataSize }}\n\t| objs: {{ .ObjsSize }}\n",
+		"stat.repo.shards.note":           "\t| shards: {{ .Shards }}\n",
+		"stat.repo.cache.note":            "\t| cache: {{ .Hits }} hits / {{ .Misses }} misses\n",
+		"stat.repo.namespace.note":        "\t| ns {{ .Namespace }}: {{ .Size }}\n",
+		"stat.repo.namespace.more.note":   "\t| ns +{{ .More }} more: {{ .Size }}\n",
 		"stat.game.mine.note":             "Mine-sweeper-game:\n\t| running: {{.Running}}\n\t| active: {{.Active}}\n\t| total: {{.Total}}",
 		"lang.note":                       "@{{ .Username }}\nLanguage updated successfully",
 		"lang.chat.note":                  "@{{ .Username }}\nThe default language for chat group {{ .ChatName }} has been successfully updated",
@@ -50,10 +54,52 @@ var templates = map[string]map[string]string{
 		"mine.game.menu.rank.button":      "Leaderboard",
 		"mine.game.menu.classic.button":   "Classic",
 		"mine.game.rank.start.note":       "@{{ .Username }}\nWelcome to the entertainment service provided by ocha.  If you successfully complete this Minesweeper challenge, your result will be added to the leaderboard. You have started a new {{ .Width }} × {{ .Height }} Minesweeper map with {{ .Mines }} mines in total.",
-		"mine.game.rank.win.note":         "@{{ .Username }}\nCongratulations! 🎉\nYou successfully completed the game in {{ .Seconds }} seconds.\nLeaderboard score\\rank: {{ .Score }}\\{{ .Rank }}\nMap size: {{ .Width }} × {{ .Height }}\nMine count: {{ .Mines }}\nUse this command to view the full leaderboard:\n/mine_rank@{{ .BotName }}",
-		"mine.game.rank.lose.note":        "@{{ .Username }}\nBoom! 💣\nUnfortunately, this run did not qualify for the leaderboard.\nTime taken: {{ .Seconds }} seconds.\nMap size: {{ .Width }} × {{ .Height }}\nMine count: {{ .Mines }}\nUse this command to view the full leaderboard:\n/mine_rank@{{ .BotName }}",
-		"mine.game.rank.res.note":         "@{{ .Username }}\nHere is the current Minesweeper leaderboard:\n<blockquote expandable>{{.RankLines}}</blockquote>\nLast updated: {{.Update}}",
+		"mine.game.rank.win.note":         "@{{ .Username }}\nCongratulations! 🎉\nYou successfully completed the game in {{ .Seconds }} seconds.\nLeaderboard score\\rank: {{ .Score }}\\{{ .Rank }}\n{{ .Best }}\nMap size: {{ .Width }} × {{ .Height }}\nMine count: {{ .Mines }}\nUse this command to view the full leaderboard:\n/mine_rank@{{ .BotName }} {{ .Board }}",
+		"mine.game.rank.best.new.note":    "🌟 New personal best!",
+		"mine.game.rank.best.old.note":    "Your personal best on this board is still higher.",
+		"mine.game.rank.lose.note":        "@{{ .Username }}\nBoom! 💣\nUnfortunately, this run did not qualify for the leaderboard.\nTime taken: {{ .Seconds }} seconds.\nMap size: {{ .Width }} × {{ .Height }}\nMine count: {{ .Mines }}\nUse this command to view the full leaderboard:\n/mine_rank@{{ .BotName }} {{ .Board }}",
+		"mine.game.rank.res.note":         "@{{ .Username }}\nHere is the current Minesweeper leaderboard of {{ .Board }}:\n<blockquote expandable>{{.RankLines}}</blockquote>\nPage {{.Page}}/{{.Pages}} · Last updated: {{.Update}}",
 		"mine.game.rank.line.note":        "Rank: {{.Index}}\n\t|User: {{.Username}}\n\t|Map size: {{ .Width }} × {{ .Height }}\n\t|Mines: {{ .Mines }}\n\t|Steps: {{ .Steps }}\n\t|Duration: {{.Duration}}\n\t|Score: {{.Score}}\n\n",
+		"mine.game.rank.prev.button":      "« Prev",
+		"mine.game.rank.next.button":      "Next »",
+		"mine.game.rank.me.button":        "Jump to me",
+		"mine.rating.res.note":            "@{{.Username}}\nYour Minesweeper rating: <b>{{.Rating}}</b> ± {{.Deviation}}\nRanked games: {{.Games}} · Wins: {{.Wins}}\n\nTop players:\n<blockquote expandable>{{.Lines}}</blockquote>",
+		"mine.rating.none.note":           "@{{.Username}}\nYou have no Minesweeper rating yet, play a ranked game to get one.\n\nTop players:\n<blockquote expandable>{{.Lines}}</blockquote>",
+		"mine.rating.line.note":           "{{.Index}}. {{.Username}}: {{.Rating}} ± {{.Deviation}} ({{.Games}} games)\n",
+		"mine.game.rank.mod.button":       "🛠 Moderate",
+		"mine.game.rank.done.button":      "✔ Done",
+		"mine.game.rank.rm.button":        "✖ {{ .Index }}",
+		"mine.game.rank.ban.button":       "⛔ {{ .Index }}",
+		"mine.mod.denied.note":            "Only bot owners, and admins on the leaderboards of their chat, may do this.",
+		"mine.mod.gone.note":              "This entry is no longer on the leaderboard.",
+		"mine.mod.removed.note":           "Removed the game of {{ .Target }} from {{ .Removed }} leaderboards.",
+		"mine.mod.banned.note":            "Banned {{ .Target }} from ranking, {{ .Removed }} entries removed.",
+		"mine.mod.ban.note":               "@{{ .Username }}\n{{ .Target }} is banned from ranking, {{ .Removed }} entries were removed.",
+		"mine.mod.unban.note":             "@{{ .Username }}\n{{ .Target }} may enter the leaderboards again.",
+		"mine.mod.notbanned.note":         "@{{ .Username }}\n{{ .Target }} is not banned.",
+		"mine.mod.usage.note":             "@{{ .Username }}\nReply to a message of the player, or give their user ID: /mine_ban &lt;user id&gt;",
+		"mine.audit.res.note":             "@{{ .Username }}\nRecent leaderboard moderation in this chat:\n<blockquote expandable>{{ .Lines }}</blockquote>",
+		"mine.audit.none.note":            "@{{ .Username }}\nThe leaderboards of this chat have not been moderated yet.",
+		"mine.audit.remove.note":          "{{ .Time }} · {{ .Actor }} removed a game of {{ .Target }} ({{ .Removed }} entries)\n",
+		"mine.audit.ban.note":             "{{ .Time }} · {{ .Actor }} banned {{ .Target }} ({{ .Removed }} entries)\n",
+		"mine.audit.unban.note":           "{{ .Time }} · {{ .Actor }} unbanned {{ .Target }}\n",
+		"mine.game.rank.custom.button":    "Up to {{ .Side }} × {{ .Side }}",
+		"mine.game.rank.scope.g.button":   "Global",
+		"mine.game.rank.scope.c.button":   "This chat",
+		"mine.game.rank.scope.t.button":   "This topic",
+		"mine.game.rank.window.a.button":  "All time",
+		"mine.game.rank.window.d.button":  "Today",
+		"mine.game.rank.window.w.button":  "This week",
+		"mine.game.rank.window.m.button":  "This month",
+		"mine.fame.res.note":              "@{{ .Username }}\nHall of fame of {{ .Board }}:\n<blockquote expandable>{{ .Lines }}</blockquote>",
+		"mine.fame.line.note":             "{{ .Title }} ({{ .Entries }} entries)\n{{ .Winners }}\n",
+		"mine.fame.winner.note":           "\t{{ .Place }}. {{ .Username }} ({{ .Score }})\n",
+		"mine.winners.week.note":          "🏆 Minesweeper winners of the week {{ .Start }} ~ {{ .End }}\n<blockquote expandable>{{ .Lines }}</blockquote>",
+		"mine.winners.on.note":            "@{{ .Username }}\nThe winners of this chat will be posted here every Monday. Send /mine_winners again to stop.",
+		"mine.winners.off.note":           "@{{ .Username }}\nThe weekly winners will no longer be posted here.",
+		"mine.winners.admin.note":         "@{{ .Username }}\nOnly admins can change the weekly winners post.",
+		"mine.winners.group.note":         "@{{ .Username }}\nWeekly winners are posted to groups only.",
+		"mine.game.rank.unknown.note":     "@{{ .Username }}\nThere is no leaderboard called {{ .Board }}. Available leaderboards: {{ .Boards }}",
 		"mine.game.start.note":            "@{{ .Username }}\nWelcome to the entertainment service provided by ocha. You have started a new {{ .Width }} × {{ .Height }} Minesweeper map.\nThere are {{ .Mines }} mines in total.",
 		"mine.game.start.button":          "Click to Start",
 		"mine.game.win.note":              "@{{ .Username }}\nCongratulations! 🎉\nYou successfully completed the game in {{ .Seconds }} seconds.\nMap size: {{ .Width }} × {{ .Height }}\nNumber of mines: {{ .Mines }}",
@@ -65,12 +111,21 @@ var templates = map[string]map[string]string{
 		"mine.game.opt.click":             "Sweep",
 		"cron.help.note":                  "@{{ .Username }}\nWelcome to the cron message service provided by ocha. \nYou can schedule message tasks here through Cron expressions to implement the function of sending messages at a scheduled time: <blockquote expandable>/cron * * * * * '<message>'\n- - - - -\n| | | | |\n| | | | +----- day of the week (0-6)\n| | | +------- month (1-12)\n| | +--------- day of the month (1-31)\n| +----------- hour (0-23)\n+------------- minute (0-59)\n\nE.g.\n/cron 0 * * * * 'hello'</blockquote>",
 		"cron.list.note":                  "@{{ .Username }}\nHere is current tasks:\n<blockquote expandable>{{.TaskLines}}</blockquote>\nLast updated: {{.Update}}",
+		"backup.private.note":             "@{{ .Username }}\nBackups hold the data of every chat, so they are only sent in a private chat with the bot.",
+		"backup.note":                     "@{{ .Username }}\nBackup created at {{ .Time }}\nRepos: {{ .Repos }}\nRecords: {{ .Records }}",
+		"restore.start.note":              "@{{ .Username }}\nThe backup of {{ .Time }} with {{ .Records }} records is valid. Restoring it, the bot is back in a moment.",
+		"restore.note":                    "@{{ .Username }}\nRestored {{ .Records }} records from the backup of {{ .Time }}.",
+		"restore.help.note":               "@{{ .Username }}\nReply to a backup archive with /restore, or send the archive with /restore as its caption.",
 		"error":                           "@{{ .Username }} Oops! Something went wrong! {{.Message}}!",
 		"help.note":                       "@{{ .Username }}\nWelcome to ocha!\nHere are some commands to help you get started:\n/mine\n/mine  &lt;width&gt; &lt;height&gt; &lt;mines&gt;\n/lang  [ zh | en | cxg ]\n/lang_chat  [ zh | en | cxg ]\n/help\n<blockquote expandable>{{.BotName}}\nAuthor: @feellmoose_dev\nVersion: {{.Version}}\nUpdated on: {{.Update}}\n</blockquote>",
 	},
 	"zh": {
 		"stat.all.note":                   "Stat report:\n<blockquote expandable>Bot:\nID: {{.BotID}}\nName: {{.BotName}}\nVersion: {{.Version}}\nUpdate: {{.Update}}\n\nRepos:\nsize: {{.RepoSize}}\n{{.Repos}}\n{{.Mine}}\n\nAnalysis:\nTime: {{.Now}}</blockquote>",
 		"stat.repo.note":                  "Repo: {{ .Name }}\n\t| type: {{ .Type }}\n\t| size: {{ .DataSize }}\n\t| objs: {{ .ObjsSize }}\n",
+		"stat.repo.shards.note":           "\t| shards: {{ .Shards }}\n",
+		"stat.repo.cache.note":            "\t| cache: {{ .Hits }} hits / {{ .Misses }} misses\n",
+		"stat.repo.namespace.note":        "\t| ns {{ .Namespace }}: {{ .Size }}\n",
+		"stat.repo.namespace.more.note":   "\t| ns +{{ .More }} more: {{ .Size }}\n",
 		"stat.game.mine.note":             "Mine-sweeper-game:\n\t| running: {{.Running}}\n\t| active: {{.Active}}\n\t| total: {{.Total}}",
 		"lang.note":                       "@{{ .Username }}\n语言修改成功",
 		"lang.chat.note":                  "@{{ .Username }}\n本聊天群组 {{ .ChatName }} 的默认语言修改成功",
@@ -91,10 +146,52 @@ var templates = map[string]map[string]string{
 		"mine.game.menu.rank.button":      "天梯赛",
 		"mine.game.menu.classic.button":   "经典模式",
 		"mine.game.rank.start.note":       "@{{ .Username }}\n欢迎使用 ocha 为您提供的娱乐服务，若本次扫雷任务成功，则会被记录在天梯赛榜单内。您已开始一个新的 {{ .Width }} × {{ .Height }} 扫雷地图。\n共有 {{ .Mines }} 个地雷",
-		"mine.game.rank.win.note":         "@{{ .Username }}\n恭喜！🎉\n您成功在 {{ .Seconds }} 秒内完成了游戏。\n天梯赛得分\\排位：{{ .Score }}\\{{ .Rank }}\n地图尺寸：{{ .Width }} × {{ .Height }}\n地雷数量：{{ .Mines }}\n使用指令查看详细榜单:\n/mine_rank@{{.BotName}}",
-		"mine.game.rank.lose.note":        "@{{ .Username }}\n砰！💣\n很遗憾，此次记录未能加入天梯赛排位中。\n耗时：{{ .Seconds }} 秒。\n地图尺寸：{{ .Width }} × {{ .Height }}\n地雷数量：{{ .Mines }}\n使用指令查看详细榜单:\n/mine_rank@{{.BotName}}",
-		"mine.game.rank.res.note":         "@{{.Username}}\n当前的扫雷天梯榜单如下：\n<blockquote expandable>{{.RankLines}}</blockquote>\n更新时间：{{.Update}}",
+		"mine.game.rank.win.note":         "@{{ .Username }}\n恭喜！🎉\n您成功在 {{ .Seconds }} 秒内完成了游戏。\n天梯赛得分\\排位：{{ .Score }}\\{{ .Rank }}\n{{ .Best }}\n地图尺寸：{{ .Width }} × {{ .Height }}\n地雷数量：{{ .Mines }}\n使用指令查看详细榜单:\n/mine_rank@{{.BotName}} {{.Board}}",
+		"mine.game.rank.best.new.note":    "🌟 刷新了个人最佳！",
+		"mine.game.rank.best.old.note":    "本次未超过您在该地图的个人最佳。",
+		"mine.game.rank.lose.note":        "@{{ .Username }}\n砰！💣\n很遗憾，此次记录未能加入天梯赛排位中。\n耗时：{{ .Seconds }} 秒。\n地图尺寸：{{ .Width }} × {{ .Height }}\n地雷数量：{{ .Mines }}\n使用指令查看详细榜单:\n/mine_rank@{{.BotName}} {{.Board}}",
+		"mine.game.rank.res.note":         "@{{.Username}}\n当前{{.Board}}的扫雷天梯榜单如下：\n<blockquote expandable>{{.RankLines}}</blockquote>\n第 {{.Page}}/{{.Pages}} 页 · 更新时间：{{.Update}}",
 		"mine.game.rank.line.note":        "排行：{{.Index}}\n\t|用户：{{.Username}}\n\t|地图：{{ .Width }} × {{ .Height }}\n\t|雷数：{{ .Mines }}\n\t|步数：{{ .Steps }}\n\t|用时：{{.Duration}}\n\t|最终得分：{{.Score}}\n\n",
+		"mine.game.rank.prev.button":      "« 上一页",
+		"mine.game.rank.next.button":      "下一页 »",
+		"mine.game.rank.me.button":        "我的排名",
+		"mine.rating.res.note":            "@{{.Username}}\n你的扫雷等级分：<b>{{.Rating}}</b> ± {{.Deviation}}\n天梯局数：{{.Games}} · 胜场：{{.Wins}}\n\n等级分排行：\n<blockquote expandable>{{.Lines}}</blockquote>",
+		"mine.rating.none.note":           "@{{.Username}}\n你还没有扫雷等级分，完成一局天梯赛后即可获得。\n\n等级分排行：\n<blockquote expandable>{{.Lines}}</blockquote>",
+		"mine.rating.line.note":           "{{.Index}}. {{.Username}}：{{.Rating}} ± {{.Deviation}}（{{.Games}} 局）\n",
+		"mine.game.rank.mod.button":       "🛠 管理",
+		"mine.game.rank.done.button":      "✔ 完成",
+		"mine.game.rank.rm.button":        "✖ {{ .Index }}",
+		"mine.game.rank.ban.button":       "⛔ {{ .Index }}",
+		"mine.mod.denied.note":            "只有机器人所有者，以及本群管理员（仅限本群榜单）可以这样做。",
+		"mine.mod.gone.note":              "该记录已不在榜单上。",
+		"mine.mod.removed.note":           "已从 {{ .Removed }} 个榜单移除 {{ .Target }} 的这局游戏。",
+		"mine.mod.banned.note":            "已禁止 {{ .Target }} 上榜，移除了 {{ .Removed }} 条记录。",
+		"mine.mod.ban.note":               "@{{ .Username }}\n已禁止 {{ .Target }} 上榜，移除了 {{ .Removed }} 条记录。",
+		"mine.mod.unban.note":             "@{{ .Username }}\n{{ .Target }} 可以重新上榜了。",
+		"mine.mod.notbanned.note":         "@{{ .Username }}\n{{ .Target }} 没有被禁止上榜。",
+		"mine.mod.usage.note":             "@{{ .Username }}\n请回复该玩家的消息，或提供其用户 ID：/mine_ban &lt;用户 ID&gt;",
+		"mine.audit.res.note":             "@{{ .Username }}\n本群最近的榜单管理记录：\n<blockquote expandable>{{ .Lines }}</blockquote>",
+		"mine.audit.none.note":            "@{{ .Username }}\n本群的榜单还没有管理记录。",
+		"mine.audit.remove.note":          "{{ .Time }} · {{ .Actor }} 移除了 {{ .Target }} 的一局游戏（{{ .Removed }} 条记录）\n",
+		"mine.audit.ban.note":             "{{ .Time }} · {{ .Actor }} 禁止了 {{ .Target }} 上榜（{{ .Removed }} 条记录）\n",
+		"mine.audit.unban.note":           "{{ .Time }} · {{ .Actor }} 解除了 {{ .Target }} 的禁止\n",
+		"mine.game.rank.custom.button":    "{{ .Side }} × {{ .Side }} 以内",
+		"mine.game.rank.scope.g.button":   "全服",
+		"mine.game.rank.scope.c.button":   "本群",
+		"mine.game.rank.scope.t.button":   "本话题",
+		"mine.game.rank.window.a.button":  "总榜",
+		"mine.game.rank.window.d.button":  "日榜",
+		"mine.game.rank.window.w.button":  "周榜",
+		"mine.game.rank.window.m.button":  "月榜",
+		"mine.fame.res.note":              "@{{.Username}}\n{{.Board}}名人堂：\n<blockquote expandable>{{.Lines}}</blockquote>",
+		"mine.fame.line.note":             "{{.Title}}（{{.Entries}} 条记录）\n{{.Winners}}\n",
+		"mine.fame.winner.note":           "\t{{.Place}}. {{.Username}}（{{.Score}}）\n",
+		"mine.winners.week.note":          "🏆 {{.Start}} ~ {{.End}} 本周扫雷优胜者\n<blockquote expandable>{{.Lines}}</blockquote>",
+		"mine.winners.on.note":            "@{{.Username}}\n每周一将在这里公布本群的优胜者。再次发送 /mine_winners 可以关闭。",
+		"mine.winners.off.note":           "@{{.Username}}\n已停止在这里公布每周优胜者。",
+		"mine.winners.admin.note":         "@{{.Username}}\n只有管理员可以修改每周优胜者公告。",
+		"mine.winners.group.note":         "@{{.Username}}\n每周优胜者只会在群组中公布。",
+		"mine.game.rank.unknown.note":     "@{{ .Username }}\n没有名为 {{ .Board }} 的榜单。可用的榜单：{{ .Boards }}",
 		"mine.game.start.note":            "@{{ .Username }}\n欢迎使用 ocha 为您提供的娱乐服务，您已开始一个新的 {{ .Width }} × {{ .Height }} 扫雷地图。\n共有 {{ .Mines }} 个地雷",
 		"mine.game.start.button":          "点击开始",
 		"mine.game.win.note":              "@{{ .Username }}\n恭喜！🎉\n您成功在 {{ .Seconds }} 秒内完成了游戏。\n地图尺寸：{{ .Width }} × {{ .Height }}\n地雷数量：{{ .Mines }}",
@@ -106,12 +203,21 @@ var templates = map[string]map[string]string{
 		"mine.game.opt.click":             "扫雷",
 		"cron.help.note":                  "@{{ .Username }}\n欢迎使用 ocha 为您提供的cron定时消息服务. \n以下是使用样例: <blockquote expandable>/cron * * * * * '<message>'\n- - - - -\n| | | | |\n| | | | +----- 周 (0-6)\n| | | +------- 月 (1-12)\n| | +--------- 日 (1-31)\n| +----------- 时 (0-23)\n+------------- 分 (0-59)\n\nE.g.\n/cron 0 * * * * 'hello'</blockquote>",
 		"cron.list.note":                  "@{{ .Username }}\n活跃任务:\n<blockquote expandable>{{.TaskLines}}</blockquote>\n更新时间: {{.Update}}",
+		"backup.private.note":             "@{{ .Username }}\n备份包含所有聊天的数据，因此只会在与机器人的私聊中发送。",
+		"backup.note":                     "@{{ .Username }}\n备份创建于 {{ .Time }}\n仓库：{{ .Repos }}\n记录数：{{ .Records }}",
+		"restore.start.note":              "@{{ .Username }}\n创建于 {{ .Time }} 的备份有效，共 {{ .Records }} 条记录。正在恢复，机器人稍后回来。",
+		"restore.note":                    "@{{ .Username }}\n已从 {{ .Time }} 的备份恢复 {{ .Records }} 条记录。",
+		"restore.help.note":               "@{{ .Username }}\n请使用 /restore 回复备份文件，或在发送备份文件时将 /restore 作为说明文字。",
 		"error":                           "@{{ .Username }} 哎呀！出了点问题！{{.Message}}！",
 		"help.note":                       "@{{ .Username }}\n欢迎使用 ocha ！\n以下是一些帮助您入门的命令：\n/mine\n/mine  &lt; 宽 &gt; &lt; 高 &gt; &lt; 雷数 &gt;\n/lang  [ zh | en | cxg ]\n/lang_chat  [ zh | en | cxg ]\n/help\n<blockquote expandable>{{.BotName}}\n作者: @feellmoose_dev\n版本信息:{{.Version}}\n更新于:{{.Update}}\n</blockquote>",
 	},
 	"cxg": {
 		"stat.all.note":                   "Stat report:\n<blockquote expandable>Bot:\nID: {{.BotID}}\nName: {{.BotName}}\nVersion: {{.Version}}\nUpdate: {{.Update}}\n\nRepos:\nsize: {{.RepoSize}}\n{{.Repos}}\n{{.Mine}}\n\nAnalysis:\nTime: {{.Now}}</blockquote>",
 		"stat.repo.note":                  "Repo: {{ .Name }}\n\t| type: {{ .Type }}\n\t| size: {{ .DataSize }}\n\t| objs: {{ .ObjsSize }}\n",
+		"stat.repo.shards.note":           "\t| shards: {{ .Shards }}\n",
+		"stat.repo.cache.note":            "\t| cache: {{ .Hits }} hits / {{ .Misses }} misses\n",
+		"stat.repo.namespace.note":        "\t| ns {{ .Namespace }}: {{ .Size }}\n",
+		"stat.repo.namespace.more.note":   "\t| ns +{{ .More }} more: {{ .Size }}\n",
 		"stat.game.mine.note":             "Mine-sweeper-game:\n\t| running: {{.Running}}\n\t| active: {{.Active}}\n\t| total: {{.Total}}",
 		"lang.note":                       "@{{ .Username }}\n哼哼！本nya大人已经优雅地把你的语言换好啦！快感谢我吧！",
 		"lang.chat.note":                  "@{{ .Username }}\n哼哼！本nya大人已经优雅地把聊天群组 {{ .ChatName }} 的默认语言换好啦！快感谢我吧！",
@@ -137,16 +243,63 @@ var templates = map[string]map[string]string{
 		"mine.game.menu.rank.button":      "最新最热最好的！天梯赛！",
 		"mine.game.menu.classic.button":   "适合老年人的经典模式",
 		"mine.game.rank.start.note":       "@{{ .Username }}\n喵喵喵~你的游戏开始啦~ 只要您这次扫雷挑战完成，成绩就会被记录到天梯赛榜单上哦~ 您已踏入全新 {{ .Width }} × {{ .Height }} 扫雷地图，埋伏了 {{ .Mines }} 颗地雷",
-		"mine.game.rank.win.note":         "@{{ .Username }}\n你竟然赢了喵！？哼哼~你是不是偷偷作弊了？不然怎么可能在 {{ .Seconds }} 秒就通关。\n天梯赛得分\\排位：{{ .Score }}\\{{ .Rank }}\n地图尺寸：{{ .Width }} × {{ .Height }}\n地雷数量：{{ .Mines }}\n要看详细榜单，请键入咒语:\n/mine_rank@{{ .BotName }}",
-		"mine.game.rank.lose.note":        "@{{ .Username }}\n砰！💣\n好可惜，这次记录没能挤进天梯赛排位里…\n耗时：{{ .Seconds }} 秒\n地图尺寸：{{ .Width }} × {{ .Height }}\n地雷数量：{{ .Mines }}\n要看详细榜单，请键入咒语:\n/mine_rank@{{ .BotName }}",
-		"mine.game.rank.res.note":         "@{{.Username}}\n哦呀！这里是扫雷天梯赛的结果看板哦:\n<blockquote expandable>{{.RankLines}}</blockquote>\n更新时间: {{.Update}}",
+		"mine.game.rank.win.note":         "@{{ .Username }}\n你竟然赢了喵！？哼哼~你是不是偷偷作弊了？不然怎么可能在 {{ .Seconds }} 秒就通关。\n天梯赛得分\\排位：{{ .Score }}\\{{ .Rank }}\n{{ .Best }}\n地图尺寸：{{ .Width }} × {{ .Height }}\n地雷数量：{{ .Mines }}\n要看详细榜单，请键入咒语:\n/mine_rank@{{ .BotName }} {{ .Board }}",
+		"mine.game.rank.best.new.note":    "🌟 哼，居然刷新了个人最佳喵…",
+		"mine.game.rank.best.old.note":    "噗噗~连自己的最佳都没超过，杂鱼~",
+		"mine.game.rank.lose.note":        "@{{ .Username }}\n砰！💣\n好可惜，这次记录没能挤进天梯赛排位里…\n耗时：{{ .Seconds }} 秒\n地图尺寸：{{ .Width }} × {{ .Height }}\n地雷数量：{{ .Mines }}\n要看详细榜单，请键入咒语:\n/mine_rank@{{ .BotName }} {{ .Board }}",
+		"mine.game.rank.res.note":         "@{{.Username}}\n哦呀！这里是{{.Board}}扫雷天梯赛的结果看板哦:\n<blockquote expandable>{{.RankLines}}</blockquote>\n第 {{.Page}}/{{.Pages}} 页 · 更新时间: {{.Update}}",
 		"mine.game.rank.line.note":        "杂鱼排行：{{.Index}}\n\t|杂鱼：{{.Username}}\n\t|地图：{{ .Width }} × {{ .Height }}\n\t|雷数：{{ .Mines }}\n\t|步数：{{ .Steps }}\n\t|用时：{{.Duration}}\n\t|杂鱼得分：{{.Score}}\n\n",
+		"mine.game.rank.prev.button":      "« 上一页喵",
+		"mine.game.rank.next.button":      "下一页喵 »",
+		"mine.game.rank.me.button":        "杂鱼在哪里？",
+		"mine.rating.res.note":            "@{{.Username}}\n杂鱼的扫雷等级分才 <b>{{.Rating}}</b> ± {{.Deviation}} 呢~\n天梯局数：{{.Games}} · 胜场：{{.Wins}}\n\n比杂鱼厉害的人们：\n<blockquote expandable>{{.Lines}}</blockquote>",
+		"mine.rating.none.note":           "@{{.Username}}\n杂鱼连等级分都没有呢~ 先去打一局天梯赛吧！\n\n比杂鱼厉害的人们：\n<blockquote expandable>{{.Lines}}</blockquote>",
+		"mine.rating.line.note":           "{{.Index}}. {{.Username}}：{{.Rating}} ± {{.Deviation}}（{{.Games}} 局）\n",
+		"mine.game.rank.mod.button":       "🛠 管理",
+		"mine.game.rank.done.button":      "✔ 完成",
+		"mine.game.rank.rm.button":        "✖ {{ .Index }}",
+		"mine.game.rank.ban.button":       "⛔ {{ .Index }}",
+		"mine.mod.denied.note":            "杂鱼也想管榜单？只有主人和本群管理员才可以喵~",
+		"mine.mod.gone.note":              "这条记录已经不在榜单上了喵~",
+		"mine.mod.removed.note":           "哼哼，{{ .Target }} 的这局已经从 {{ .Removed }} 个榜单上消失了喵~",
+		"mine.mod.banned.note":            "{{ .Target }} 被赶出榜单啦，清掉了 {{ .Removed }} 条记录喵~",
+		"mine.mod.ban.note":               "@{{ .Username }}\n{{ .Target }} 被本nya大人赶出榜单啦，清掉了 {{ .Removed }} 条记录喵~",
+		"mine.mod.unban.note":             "@{{ .Username }}\n好吧好吧，{{ .Target }} 可以回榜单了喵。",
+		"mine.mod.notbanned.note":         "@{{ .Username }}\n{{ .Target }} 本来就没被赶出去喵？",
+		"mine.mod.usage.note":             "@{{ .Username }}\n要回复那只杂鱼的消息，或者告诉本nya大人它的用户 ID 喵：/mine_ban &lt;用户 ID&gt;",
+		"mine.audit.res.note":             "@{{ .Username }}\n本群最近的榜单管理记录喵：\n<blockquote expandable>{{ .Lines }}</blockquote>",
+		"mine.audit.none.note":            "@{{ .Username }}\n本群的榜单还很干净，没有管理记录喵~",
+		"mine.audit.remove.note":          "{{ .Time }} · {{ .Actor }} 移除了 {{ .Target }} 的一局（{{ .Removed }} 条记录）\n",
+		"mine.audit.ban.note":             "{{ .Time }} · {{ .Actor }} 把 {{ .Target }} 赶出了榜单（{{ .Removed }} 条记录）\n",
+		"mine.audit.unban.note":           "{{ .Time }} · {{ .Actor }} 放 {{ .Target }} 回了榜单\n",
+		"mine.game.rank.custom.button":    "{{ .Side }} × {{ .Side }} 以内的大地图",
+		"mine.game.rank.scope.g.button":   "全世界的杂鱼",
+		"mine.game.rank.scope.c.button":   "本群的杂鱼",
+		"mine.game.rank.scope.t.button":   "本话题的杂鱼",
+		"mine.game.rank.window.a.button":  "永远的杂鱼",
+		"mine.game.rank.window.d.button":  "今天的杂鱼",
+		"mine.game.rank.window.w.button":  "本周的杂鱼",
+		"mine.game.rank.window.m.button":  "本月的杂鱼",
+		"mine.fame.res.note":              "@{{ .Username }}\n这里是{{ .Board }}的名人堂喵，才不是为了夸你们呢:\n<blockquote expandable>{{ .Lines }}</blockquote>",
+		"mine.fame.line.note":             "{{ .Title }}（{{ .Entries }} 只杂鱼参加）\n{{ .Winners }}\n",
+		"mine.fame.winner.note":           "\t{{ .Place }}. {{ .Username }}（{{ .Score }}）\n",
+		"mine.winners.week.note":          "🏆 {{ .Start }} ~ {{ .End }} 本周最不杂鱼的杂鱼们出炉啦喵~\n<blockquote expandable>{{ .Lines }}</blockquote>",
+		"mine.winners.on.note":            "@{{ .Username }}\n哼哼~每周一本nya大人都会来这里公布优胜的杂鱼哦！再发一次 /mine_winners 就不来了喵。",
+		"mine.winners.off.note":           "@{{ .Username }}\n好吧好吧，本nya大人以后不来公布了喵。",
+		"mine.winners.admin.note":         "@{{ .Username }}\n杂鱼不是管理员，才不听你的喵！",
+		"mine.winners.group.note":         "@{{ .Username }}\n优胜杂鱼只在群里公布哦~",
+		"mine.game.rank.unknown.note":     "@{{ .Username }}\n喵？才没有叫 {{ .Board }} 的榜单呢！能看的榜单只有这些哦：{{ .Boards }}",
 		"mine.game.menu.note":             "@{{ .Username }}\n欢迎来到本nya大人精心布置的雷之乐园~♡\n喵呼呼~快选个难度试试看你能撑几步喵？别怕爆炸哦，本nya大人会在一旁看好戏的~♪",
 		"mine.game.start.note":            "@{{ .Username }}\n喵喵喵~你的游戏开始啦~ \n尺寸：{{ .Width }} × {{ .Height }}，地雷数：{{ .Mines }} 个。\n本nya大人已经布好雷，等你来踩爆~♡",
 		"mine.game.win.note":              "@{{ .Username }}\n你竟然赢了喵！？哼哼~你是不是偷偷作弊了？不然怎么可能在 {{ .Seconds }} 秒就完成地图：{{ .Width }}×{{ .Height }}，地雷数：{{ .Mines }} 个！\n本nya大人才没那么容易认输呢~下次让你哭着投降！",
 		"mine.game.lose.note":             "@{{ .Username }}\n砰～💣哇咔咔~你爆炸啦~本nya大人就知道你会踩雷喵！\n时间：{{ .Seconds }} 秒，地图：{{ .Width }}×{{ .Height }}，雷数：{{ .Mines }}。\n可怜兮兮的小笨蛋，要不要本nya大人抱抱呀~？嘻嘻~",
 		"cron.help.note":                  "@{{ .Username }}\n迷路的小猫咪要找帮助吗？本nya大人大发慈悲告诉你一点线索喵~\ncron是这样用的喵: <blockquote expandable>/cron * * * * * '<message>'\n- - - - -\n| | | | |\n| | | | +----- 周 (0-6)\n| | | +------- 月 (1-12)\n| | +--------- 日 (1-31)\n| +----------- 时 (0-23)\n+------------- 分 (0-59)\n\nE.g.\n/cron 0 * * * * 'hello'</blockquote>",
 		"cron.list.note":                  "@{{ .Username }}\n目前在线的任务喵:\n<blockquote expandable>{{.TaskLines}}</blockquote>\n更新时间: {{.Update}}",
+		"backup.private.note":             "@{{ .Username }}\n笨蛋~小本本里有所有人的秘密，才不会在这里给你呢！来私聊本nya大人喵~",
+		"backup.note":                     "@{{ .Username }}\n本nya大人在 {{ .Time }} 把小本本全部抄了一份喵~\n仓库：{{ .Repos }}\n记录数：{{ .Records }}",
+		"restore.start.note":              "@{{ .Username }}\n哼哼~ {{ .Time }} 的小本本没问题，一共 {{ .Records }} 条记录喵！本喵这就塞回去，等一下下喵~",
+		"restore.note":                    "@{{ .Username }}\n{{ .Time }} 的小本本已经塞回去了，一共 {{ .Records }} 条记录喵！夸夸本nya大人吧~",
+		"restore.help.note":               "@{{ .Username }}\n笨蛋~要用 /restore 回复备份文件，或者发文件的时候把 /restore 写在说明里喵！",
 		"error":                           "@{{ .Username }} 哎呀出错了喵~ 你果然不行呢~连 {{ .Message }} 都搞不清楚~要不要本nya大人教教你啊？喵呼呼~",
 		"help.note":                       "@{{ .Username }}\n迷路的小猫咪要找帮助吗？本nya大人大发慈悲告诉你一点线索喵~\n/mine\n/mine  &lt; 宽 &gt; &lt; 高 &gt; &lt; 雷数 &gt;\n/cron * * * * * '<message>'\n/lang  [ zh | en | cxg ]\n/lang_chat  [ zh | en | cxg ]\n/help\n<blockquote expandable>{{.BotName}}\n作者: @feellmoose_dev\n版本：{{.Version}}\n更新时间：{{.Update}}\n</blockquote>",
 	},
@@ -0,0 +1,175 @@
+package helper
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Namespace scopes the keys of one kind of owner within a shared repo.
+type Namespace string
+
+const (
+	NamespaceUser   Namespace = "us"
+	NamespaceChat   Namespace = "ch"
+	NamespaceTopic  Namespace = "tp"
+	NamespaceGlobal Namespace = "gl"
+)
+
+const namespaceSeparator = "_"
+
+// ID formats a user or chat ID as a namespaced key.
+func ID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// TopicID formats the key of a forum topic of chat.
+func TopicID(chat int64, topic int) string {
+	return ID(chat) + namespaceSeparator + strconv.Itoa(topic)
+}
+
+// NamespaceInfo reports the number of keys in each namespace of a repo.
+type NamespaceInfo interface {
+	Name() string
+	NamespaceSizes() map[Namespace]int
+}
+
+// Namespaces splits one repo into disjoint keyspaces. Each namespace can be
+// claimed only once, so two features cannot share keys by accident.
+type Namespaces[T any] struct {
+	repo  Repo[T]
+	mu    sync.Mutex
+	names map[Namespace]bool
+}
+
+func NewNamespaces[T any](repo Repo[T]) *Namespaces[T] {
+	return &Namespaces[T]{
+		repo:  repo,
+		names: make(map[Namespace]bool),
+	}
+}
+
+// Sub returns the repo of the keys in ns. It panics if ns is already claimed
+// or contains the separator, which would make it overlap another namespace.
+func (n *Namespaces[T]) Sub(ns Namespace) *SubRepo[T] {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if ns == "" || strings.Contains(string(ns), namespaceSeparator) {
+		log.Panicf("Error create namespace %q: invalid name", ns)
+	}
+	if n.names[ns] {
+		log.Panicf("Error create namespace %q: already claimed", ns)
+	}
+	n.names[ns] = true
+	return &SubRepo[T]{repo: n.repo, prefix: string(ns) + namespaceSeparator}
+}
+
+// Release deletes the keys of ns and frees the namespace to be claimed again.
+func (n *Namespaces[T]) Release(ns Namespace) {
+	n.mu.Lock()
+	delete(n.names, ns)
+	n.mu.Unlock()
+
+	prefix := string(ns) + namespaceSeparator
+	var keys []string
+	n.repo.Range(func(key string, _ T) bool {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	for _, key := range keys {
+		n.repo.Del(key)
+	}
+}
+
+func (n *Namespaces[T]) Name() string {
+	if info, ok := n.repo.(RepoInfo); ok {
+		return info.Name()
+	}
+	return ""
+}
+
+// NamespaceSizes counts the keys of every claimed namespace.
+func (n *Namespaces[T]) NamespaceSizes() map[Namespace]int {
+	n.mu.Lock()
+	sizes := make(map[Namespace]int, len(n.names))
+	for ns := range n.names {
+		sizes[ns] = 0
+	}
+	n.mu.Unlock()
+
+	n.repo.Range(func(key string, _ T) bool {
+		if ns, _, ok := strings.Cut(key, namespaceSeparator); ok {
+			if _, claimed := sizes[Namespace(ns)]; claimed {
+				sizes[Namespace(ns)]++
+			}
+		}
+		return true
+	})
+	return sizes
+}
+
+// SubRepo is the part of a shared repo within one namespace. Its keys are
+// relative to the namespace, and Range only visits keys of the namespace.
+type SubRepo[T any] struct {
+	repo   Repo[T]
+	prefix string
+}
+
+func (s *SubRepo[T]) Get(key string) (T, bool) {
+	return s.repo.Get(s.prefix + key)
+}
+
+func (s *SubRepo[T]) Put(key string, value T) bool {
+	return s.repo.Put(s.prefix+key, value)
+}
+
+func (s *SubRepo[T]) PutWithTTL(key string, value T, ttl time.Duration) bool {
+	return s.repo.PutWithTTL(s.prefix+key, value, ttl)
+}
+
+func (s *SubRepo[T]) Expire(key string, ttl time.Duration) bool {
+	return s.repo.Expire(s.prefix+key, ttl)
+}
+
+func (s *SubRepo[T]) Del(key string) bool {
+	return s.repo.Del(s.prefix + key)
+}
+
+func (s *SubRepo[T]) Update(key string, f func(old T, exists bool) (T, bool)) (T, bool) {
+	return s.repo.Update(s.prefix+key, f)
+}
+
+func (s *SubRepo[T]) Reserve(key string, ttl time.Duration) bool {
+	if r, ok := s.repo.(Reserver); ok {
+		return r.Reserve(s.prefix+key, ttl)
+	}
+	return false
+}
+
+func (s *SubRepo[T]) Range(f func(key string, value T) bool) {
+	s.repo.Range(func(key string, value T) bool {
+		if rest, ok := strings.CutPrefix(key, s.prefix); ok {
+			return f(rest, value)
+		}
+		return true
+	})
+}
+
+// Stop does nothing; the shared repo is stopped by its owner.
+func (s *SubRepo[T]) Stop() {}
+
+func (s *SubRepo[T]) Watch(prefix string, f func(Event[T])) (cancel func()) {
+	w, ok := s.repo.(Watcher[T])
+	if !ok {
+		return func() {}
+	}
+	return w.Watch(s.prefix+prefix, func(e Event[T]) {
+		e.Key = strings.TrimPrefix(e.Key, s.prefix)
+		f(e)
+	})
+}
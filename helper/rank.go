@@ -1,81 +1,184 @@
 package helper
 
 import (
-	"container/heap"
-	"sort"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 )
 
 type RankItem[T any] struct {
+	// ID identifies the item within its rank, see Remove.
+	ID    string
 	Index int
 	Score float64
 	Item  T
+	// Best reports whether Add improved the best item of its owner, see
+	// WithOwner.
+	Best bool
 }
 
 type Ranker[T any] interface {
 	Items() []RankItem[T]
 	At(index int) (RankItem[T], bool)
 	Add(item T) RankItem[T]
+	// Len returns the number of items.
+	Len() int
+	// Range calls f on the items from index start on, in order, until f
+	// returns false.
+	Range(start int, f func(RankItem[T]) bool)
+	// RankOf returns the item of owner, see WithOwner.
+	RankOf(owner string) (RankItem[T], bool)
+	// Remove deletes the item of id and returns it.
+	Remove(id string) (RankItem[T], bool)
+	// Ban deletes the items of owner and keeps them out until they are
+	// unbanned, see WithBans. It returns the deleted items.
+	Ban(owner string) []RankItem[T]
 }
 
-type heapItem[T any] struct {
-	id    string
-	score float64
-	item  T
+// RankBan keeps an owner out of the ranks sharing the bans repo.
+type RankBan struct {
+	Since time.Time `json:"since,omitempty"`
 }
 
-type scoreHeap[T any] []*heapItem[T]
+// RankOption configures a QueueRank.
+type RankOption[T any] func(*rankConfig[T])
 
-func (h *scoreHeap[T]) Len() int {
-	return len(*h)
+type rankConfig[T any] struct {
+	owner  func(T) string
+	latest bool
+	bans   Repo[RankBan]
 }
 
-func (h *scoreHeap[T]) Less(i, j int) bool {
-	return (*h)[i].score < (*h)[j].score
-}
-
-func (h *scoreHeap[T]) Swap(i, j int) {
-	(*h)[i], (*h)[j] = (*h)[j], (*h)[i]
+// WithOwner keeps only the best item of every owner, e.g. one entry per
+// user. Add of an item that does not beat the best of its owner leaves the
+// rank unchanged and returns the best.
+func WithOwner[T any](owner func(T) string) RankOption[T] {
+	return func(cfg *rankConfig[T]) {
+		cfg.owner = owner
+	}
 }
 
-func (h *scoreHeap[T]) Push(x any) {
-	*h = append(*h, x.(*heapItem[T]))
+// WithLatest keeps the latest item of every owner instead of the best, e.g.
+// a rating that may fall. It requires WithOwner.
+func WithLatest[T any]() RankOption[T] {
+	return func(cfg *rankConfig[T]) {
+		cfg.latest = true
+	}
 }
 
-func (h *scoreHeap[T]) Pop() any {
-	old := *h
-	n := len(old)
-	it := old[n-1]
-	*h = old[:n-1]
-	return it
+// WithBans stores the banned owners in bans, keyed by owner, so that a ban
+// outlives restarts and holds in every rank sharing bans. It requires
+// WithOwner.
+func WithBans[T any](bans Repo[RankBan]) RankOption[T] {
+	return func(cfg *rankConfig[T]) {
+		cfg.bans = bans
+	}
 }
 
+// QueueRank keeps the capacity best items by score. Add, At, RankOf and the
+// start of Range take O(log n). Items are keyed by time ordered IDs, so the
+// order of tied scores, the earlier added first, is restored from the keys
+// after a restart.
 type QueueRank[T any] struct {
 	mu       sync.Mutex
 	capacity int
-	heap     scoreHeap[T]
+	list     *rankList[T]
 	repo     Repo[T]
 	id       GenID
+	owner    func(T) string
+	latest   bool
+	bans     Repo[RankBan]
+	owners   map[string]*rankNode[T]
+	nodes    map[string]*rankNode[T]
 	Score    func(T) float64
 }
 
-func NewQueueRank[T any](repo Repo[T], capacity int, score func(T) float64) *QueueRank[T] {
-	h := make(scoreHeap[T], 0, capacity)
+func NewQueueRank[T any](repo Repo[T], capacity int, score func(T) float64, opts ...RankOption[T]) *QueueRank[T] {
+	var cfg rankConfig[T]
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	snowflake, _ := NewSnowflakeID(0)
+	q := &QueueRank[T]{
+		repo:     repo,
+		id:       snowflake,
+		capacity: capacity,
+		list:     newRankList[T](),
+		owner:    cfg.owner,
+		latest:   cfg.latest,
+		bans:     cfg.bans,
+		owners:   make(map[string]*rankNode[T]),
+		nodes:    make(map[string]*rankNode[T]),
+		Score:    score,
+	}
+
+	// items stored before owners were tracked may repeat an owner
+	var (
+		stale  []string
+		stored = make(map[string]T)
+	)
 	repo.Range(func(key string, value T) bool {
-		h = append(h, &heapItem[T]{
+		stored[key] = value
+		return true
+	})
+	for _, key := range rankKeys(stored) {
+		value := stored[key]
+		n := &rankNode[T]{
 			id:    key,
 			score: score(value),
 			item:  value,
-		})
-		return true
+		}
+		if q.owner != nil {
+			o := q.owner(value)
+			if best, ok := q.owners[o]; ok {
+				if best.score >= n.score {
+					stale = append(stale, key)
+					continue
+				}
+				stale = append(stale, best.id)
+				q.list.remove(best)
+				delete(q.nodes, best.id)
+			}
+			q.owners[o] = n
+		}
+		q.list.insert(n)
+		q.nodes[key] = n
+	}
+	for _, key := range stale {
+		repo.Del(key)
+	}
+	return q
+}
+
+// rankKeys returns the keys of stored items in the order they were added:
+// keys given before QueueRank used time ordered IDs first, then the time
+// ordered ones, which sort by the time they were given.
+func rankKeys[T any](stored map[string]T) []string {
+	keys := slices.Collect(maps.Keys(stored))
+	slices.SortFunc(keys, func(a, b string) int {
+		if la, lb := len(a) != snowflakeLen, len(b) != snowflakeLen; la != lb {
+			if la {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
 	})
-	heap.Init(&h)
-	return &QueueRank[T]{
-		repo:     repo,
-		id:       NewGenRandomRepoShortID(4, 16, 5, repo),
-		capacity: capacity,
-		heap:     h,
-		Score:    score,
+	return keys
+}
+
+// nextID returns an ID not used by an item, sorting after those given before.
+func (q *QueueRank[T]) nextID() (string, error) {
+	for {
+		id, err := q.id.NextID()
+		if err != nil {
+			return "", err
+		}
+		if _, taken := q.nodes[id]; !taken {
+			return id, nil
+		}
 	}
 }
 
@@ -83,68 +186,303 @@ func (q *QueueRank[T]) Add(item T) RankItem[T] {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	id, _ := q.id.NextID()
 	score := q.Score(item)
-	hi := &heapItem[T]{
+	var (
+		o        string
+		improved = q.owner != nil
+	)
+	if q.owner != nil {
+		o = q.owner(item)
+		if q.banned(o) {
+			return RankItem[T]{Index: -1, Score: score, Item: item}
+		}
+		if best, ok := q.owners[o]; ok {
+			improved = score > best.score
+			if !improved && !q.latest {
+				return q.rankItem(best, q.list.indexOf(best), false)
+			}
+		}
+	}
+
+	// the best item of the owner is only replaced once the new one has an ID
+	id, err := q.nextID()
+	if err != nil {
+		return RankItem[T]{Index: -1, Score: score, Item: item}
+	}
+	if best, ok := q.owners[o]; ok {
+		q.remove(best)
+	}
+	n := &rankNode[T]{
 		id:    id,
 		score: score,
 		item:  item,
 	}
-	heap.Push(&q.heap, hi)
+	index := q.list.insert(n)
+	q.nodes[id] = n
 	q.repo.Put(id, item)
+	if q.owner != nil {
+		q.owners[o] = n
+	}
+
+	for q.list.length > q.capacity {
+		last, _ := q.list.at(q.list.length - 1)
+		q.remove(last)
+		if last == n {
+			index = -1
+		}
+	}
+	return q.rankItem(n, index, improved)
+}
 
-	if q.heap.Len() > q.capacity {
-		p := heap.Pop(&q.heap).(*heapItem[T])
-		q.repo.Del(p.id)
+// remove deletes n from the list, the repo and the owners.
+func (q *QueueRank[T]) remove(n *rankNode[T]) {
+	q.list.remove(n)
+	delete(q.nodes, n.id)
+	q.repo.Del(n.id)
+	if q.owner != nil && q.owners[q.owner(n.item)] == n {
+		delete(q.owners, q.owner(n.item))
 	}
+}
+
+func (q *QueueRank[T]) banned(owner string) bool {
+	if q.bans == nil {
+		return false
+	}
+	_, ok := q.bans.Get(owner)
+	return ok
+}
 
-	snapshot := make([]*heapItem[T], q.heap.Len())
-	copy(snapshot, q.heap)
+func (q *QueueRank[T]) rankItem(n *rankNode[T], index int, best bool) RankItem[T] {
+	return RankItem[T]{ID: n.id, Index: index, Score: n.score, Item: n.item, Best: best}
+}
 
-	sort.Slice(snapshot, func(i, j int) bool {
-		return snapshot[i].score > snapshot[j].score
+func (q *QueueRank[T]) Items() []RankItem[T] {
+	items := make([]RankItem[T], 0, q.Len())
+	q.Range(0, func(item RankItem[T]) bool {
+		items = append(items, item)
+		return true
 	})
+	return items
+}
 
-	var resultRank RankItem[T]
-	items := make([]RankItem[T], len(snapshot))
-	for idx, node := range snapshot {
-		items[idx] = RankItem[T]{
-			Index: idx,
-			Score: node.score,
-			Item:  node.item,
-		}
-		if node == hi {
-			resultRank = items[idx]
+func (q *QueueRank[T]) At(index int) (RankItem[T], bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n, ok := q.list.at(index)
+	if !ok {
+		return RankItem[T]{}, false
+	}
+	return q.rankItem(n, index, false), true
+}
+
+func (q *QueueRank[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.list.length
+}
+
+// Range holds the rank, so f must not call other methods of q.
+func (q *QueueRank[T]) Range(start int, f func(RankItem[T]) bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n, ok := q.list.at(start)
+	for index := start; ok && n != nil; index++ {
+		if !f(q.rankItem(n, index, false)) {
+			return
 		}
+		n = n.next[0].node
 	}
-	return resultRank
 }
 
-func (q *QueueRank[T]) Items() []RankItem[T] {
+// RankOf returns the item of owner. It is only found with WithOwner.
+func (q *QueueRank[T]) RankOf(owner string) (RankItem[T], bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n, ok := q.owners[owner]
+	if !ok {
+		return RankItem[T]{}, false
+	}
+	return q.rankItem(n, q.list.indexOf(n), false), true
+}
+
+func (q *QueueRank[T]) Remove(id string) (RankItem[T], bool) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	snapshot := make([]*heapItem[T], q.heap.Len())
-	copy(snapshot, q.heap)
-	sort.Slice(snapshot, func(i, j int) bool {
-		return snapshot[i].score > snapshot[j].score
+	n, ok := q.nodes[id]
+	if !ok {
+		return RankItem[T]{}, false
+	}
+	item := q.rankItem(n, q.list.indexOf(n), false)
+	q.remove(n)
+	return item, true
+}
+
+// Ban deletes the item of owner and, with WithBans, refuses the items of
+// owner until Unban. It only finds the item with WithOwner.
+func (q *QueueRank[T]) Ban(owner string) []RankItem[T] {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.bans != nil && !q.banned(owner) {
+		q.bans.Put(owner, RankBan{Since: time.Now()})
+	}
+	n, ok := q.owners[owner]
+	if !ok {
+		return nil
+	}
+	item := q.rankItem(n, q.list.indexOf(n), false)
+	q.remove(n)
+	return []RankItem[T]{item}
+}
+
+// Unban lets owner into the rank again. It reports whether owner was banned.
+func (q *QueueRank[T]) Unban(owner string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.banned(owner) {
+		return false
+	}
+	q.bans.Del(owner)
+	return true
+}
+
+// KeyedRank keeps a separate QueueRank for every key, e.g. one leaderboard
+// per board size, each persisted in its own namespace of repo.
+type KeyedRank[T any] struct {
+	mu         sync.Mutex
+	namespaces *Namespaces[T]
+	ranks      map[string]*QueueRank[T]
+	capacity   int
+	score      func(T) float64
+	opts       []RankOption[T]
+	bans       Repo[RankBan]
+}
+
+// NewKeyedRank loads the ranks stored in repo. Items stored before ranks
+// were keyed are moved to the rank of key(item). Every rank is created with
+// opts.
+func NewKeyedRank[T any](repo Repo[T], capacity int, score func(T) float64, key func(T) string, opts ...RankOption[T]) *KeyedRank[T] {
+	k := &KeyedRank[T]{
+		namespaces: NewNamespaces[T](repo),
+		ranks:      make(map[string]*QueueRank[T]),
+		capacity:   capacity,
+		score:      score,
+		opts:       opts,
+	}
+	var cfg rankConfig[T]
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	k.bans = cfg.bans
+
+	var (
+		keys   = make(map[string]bool)
+		legacy = make(map[string]T)
+	)
+	repo.Range(func(id string, value T) bool {
+		if ns, _, ok := strings.Cut(id, namespaceSeparator); ok {
+			keys[ns] = true
+		} else {
+			legacy[id] = value
+		}
+		return true
 	})
+	for id, value := range legacy {
+		rk := key(value)
+		repo.Put(rk+namespaceSeparator+id, value)
+		repo.Del(id)
+		keys[rk] = true
+	}
+	for rk := range keys {
+		k.Rank(rk)
+	}
+	return k
+}
+
+// Rank returns the rank of key, creating it if needed.
+func (k *KeyedRank[T]) Rank(key string) Ranker[T] {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if r, ok := k.ranks[key]; ok {
+		return r
+	}
+	r := NewQueueRank[T](k.namespaces.Sub(Namespace(key)), k.capacity, k.score, k.opts...)
+	k.ranks[key] = r
+	return r
+}
+
+// Add adds item to the rank of every key and returns its place in each.
+func (k *KeyedRank[T]) Add(item T, keys ...string) []RankItem[T] {
+	items := make([]RankItem[T], len(keys))
+	for i, key := range keys {
+		items[i] = k.Rank(key).Add(item)
+	}
+	return items
+}
 
-	items := make([]RankItem[T], len(snapshot))
-	for idx, node := range snapshot {
-		items[idx] = RankItem[T]{
-			Index: idx,
-			Score: node.score,
-			Item:  node.item,
+// Drop deletes the rank of key and returns its final items.
+func (k *KeyedRank[T]) Drop(key string) []RankItem[T] {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	r, ok := k.ranks[key]
+	if !ok {
+		return nil
+	}
+	items := r.Items()
+	delete(k.ranks, key)
+	k.namespaces.Release(Namespace(key))
+	return items
+}
+
+// Ban deletes the items of owner from every rank and, with WithBans, keeps
+// owner out of every rank until Unban. It returns the deleted items.
+func (k *KeyedRank[T]) Ban(owner string) []RankItem[T] {
+	k.mu.Lock()
+	if k.bans != nil {
+		if _, ok := k.bans.Get(owner); !ok {
+			k.bans.Put(owner, RankBan{Since: time.Now()})
 		}
 	}
+	ranks := slices.Collect(maps.Values(k.ranks))
+	k.mu.Unlock()
+
+	var items []RankItem[T]
+	for _, r := range ranks {
+		items = append(items, r.Ban(owner)...)
+	}
 	return items
 }
 
-func (q *QueueRank[T]) At(index int) (RankItem[T], bool) {
-	items := q.Items()
-	if index < 0 || index >= len(items) {
-		return RankItem[T]{}, false
+// Unban lets owner into the ranks again. It reports whether owner was
+// banned.
+func (k *KeyedRank[T]) Unban(owner string) bool {
+	if k.bans == nil {
+		return false
+	}
+	if _, ok := k.bans.Get(owner); !ok {
+		return false
 	}
-	return items[index], true
+	k.bans.Del(owner)
+	return true
+}
+
+// Keys returns the keys of all ranks in order.
+func (k *KeyedRank[T]) Keys() []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return slices.Sorted(maps.Keys(k.ranks))
+}
+
+// Namespaces reports the size of every rank.
+func (k *KeyedRank[T]) Namespaces() NamespaceInfo {
+	return k.namespaces
 }
@@ -0,0 +1,159 @@
+package helper
+
+import "math/rand/v2"
+
+const (
+	rankListMaxLevel = 32
+	// rankListP is the chance of a node to reach the next level.
+	rankListP = 4
+)
+
+type rankLink[T any] struct {
+	node *rankNode[T]
+	// span is the number of nodes the link skips, counting its target.
+	span int
+}
+
+type rankNode[T any] struct {
+	id    string
+	score float64
+	seq   uint64
+	item  T
+	next  []rankLink[T]
+}
+
+// before reports whether n ranks above o: a higher score, or the same score
+// added earlier.
+func (n *rankNode[T]) before(o *rankNode[T]) bool {
+	return n.score > o.score || n.score == o.score && n.seq < o.seq
+}
+
+// rankList is a skip list ordered from the best to the worst node, whose
+// links count the nodes they skip, so that finding a node by its index or
+// the index of a node takes O(log n).
+type rankList[T any] struct {
+	head   *rankNode[T]
+	level  int
+	length int
+	seq    uint64
+}
+
+func newRankList[T any]() *rankList[T] {
+	return &rankList[T]{
+		head:  &rankNode[T]{next: make([]rankLink[T], rankListMaxLevel)},
+		level: 1,
+	}
+}
+
+func randomRankLevel() int {
+	level := 1
+	for level < rankListMaxLevel && rand.IntN(rankListP) == 0 {
+		level++
+	}
+	return level
+}
+
+// insert adds n and returns its index.
+func (l *rankList[T]) insert(n *rankNode[T]) int {
+	var (
+		update [rankListMaxLevel]*rankNode[T]
+		rank   [rankListMaxLevel]int
+	)
+	l.seq++
+	n.seq = l.seq
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		if i < l.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.next[i].node != nil && x.next[i].node.before(n) {
+			rank[i] += x.next[i].span
+			x = x.next[i].node
+		}
+		update[i] = x
+	}
+
+	level := randomRankLevel()
+	if level > l.level {
+		for i := l.level; i < level; i++ {
+			update[i] = l.head
+			l.head.next[i].span = l.length
+		}
+		l.level = level
+	}
+	n.next = make([]rankLink[T], level)
+	for i := 0; i < level; i++ {
+		n.next[i].node = update[i].next[i].node
+		update[i].next[i].node = n
+		n.next[i].span = update[i].next[i].span - (rank[0] - rank[i])
+		update[i].next[i].span = rank[0] - rank[i] + 1
+	}
+	for i := level; i < l.level; i++ {
+		update[i].next[i].span++
+	}
+	l.length++
+	return rank[0]
+}
+
+// remove deletes n from the list.
+func (l *rankList[T]) remove(n *rankNode[T]) {
+	var update [rankListMaxLevel]*rankNode[T]
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil && x.next[i].node.before(n) {
+			x = x.next[i].node
+		}
+		update[i] = x
+	}
+	if x.next[0].node != n {
+		return
+	}
+	for i := 0; i < l.level; i++ {
+		if update[i].next[i].node == n {
+			update[i].next[i].span += n.next[i].span - 1
+			update[i].next[i].node = n.next[i].node
+		} else {
+			update[i].next[i].span--
+		}
+	}
+	for l.level > 1 && l.head.next[l.level-1].node == nil {
+		l.level--
+	}
+	l.length--
+}
+
+// indexOf returns the index of n, or -1 if n is not in the list.
+func (l *rankList[T]) indexOf(n *rankNode[T]) int {
+	rank := 0
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil && (x.next[i].node.before(n) || x.next[i].node == n) {
+			rank += x.next[i].span
+			x = x.next[i].node
+		}
+		if x == n {
+			return rank - 1
+		}
+	}
+	return -1
+}
+
+// at returns the node at index.
+func (l *rankList[T]) at(index int) (*rankNode[T], bool) {
+	if index < 0 || index >= l.length {
+		return nil, false
+	}
+	traversed := 0
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.next[i].node != nil && traversed+x.next[i].span <= index+1 {
+			traversed += x.next[i].span
+			x = x.next[i].node
+		}
+		if traversed == index+1 {
+			return x, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,285 @@
+package helper
+
+import (
+	"cmp"
+	"container/heap"
+	"github.com/go-playground/assert/v2"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestKeyedRank(t *testing.T) {
+	repo := NewMemRepo[int]("test")
+	defer repo.Stop()
+	// items stored before ranks were keyed
+	repo.Put("a", 1)
+	repo.Put("b", 12)
+
+	score := func(v int) float64 { return float64(v) }
+	key := func(v int) string { return "k" + strconv.Itoa(v/10) }
+	rank := NewKeyedRank[int](repo, 2, score, key)
+	assert.Equal(t, rank.Keys(), []string{"k0", "k1"})
+	_, ok := repo.Get("a")
+	assert.Equal(t, ok, false)
+	_, ok = repo.Get("k0_a")
+	assert.Equal(t, ok, true)
+
+	rank.Rank("k0").Add(3)
+	rank.Rank("k0").Add(2)
+	items := rank.Rank("k0").Items()
+	assert.Equal(t, len(items), 2)
+	assert.Equal(t, items[0].Item, 3)
+	assert.Equal(t, len(rank.Rank("k1").Items()), 1)
+	assert.Equal(t, rank.Namespaces().NamespaceSizes(), map[Namespace]int{"k0": 2, "k1": 1})
+
+	// ranks are reloaded from their namespaces
+	reloaded := NewKeyedRank[int](repo, 2, score, key)
+	assert.Equal(t, reloaded.Rank("k0").Items(), items)
+
+	// dropping a rank deletes its items and frees its namespace
+	dropped := reloaded.Drop("k0")
+	assert.Equal(t, len(dropped), 2)
+	assert.Equal(t, reloaded.Keys(), []string{"k1"})
+	_, ok = repo.Get("k0_a")
+	assert.Equal(t, ok, false)
+	assert.Equal(t, len(reloaded.Rank("k0").Items()), 0)
+}
+
+func TestQueueRankTiesReload(t *testing.T) {
+	type entry struct {
+		User  string
+		Score int
+	}
+	repo := NewMemRepo[entry]("test")
+	defer repo.Stop()
+	// an entry stored before IDs were time ordered
+	repo.Put("zz", entry{"u0", 1})
+
+	score := func(e entry) float64 { return float64(e.Score) }
+	rank := NewQueueRank[entry](repo, 10, score)
+	for i := 1; i <= 20; i++ {
+		rank.Add(entry{"u" + strconv.Itoa(i), 1})
+	}
+	items := rank.Items()
+	assert.Equal(t, items[0].Item.User, "u0")
+	assert.Equal(t, items[9].Item.User, "u9")
+
+	// tied scores keep the order they were added in
+	reloaded := NewQueueRank[entry](repo, 10, score)
+	assert.Equal(t, reloaded.Items(), items)
+}
+
+func TestQueueRankOwner(t *testing.T) {
+	type entry struct {
+		User  string
+		Score int
+	}
+	repo := NewMemRepo[entry]("test")
+	defer repo.Stop()
+	// entries stored before owners were tracked
+	repo.Put("a", entry{"u1", 5})
+	repo.Put("b", entry{"u1", 7})
+
+	score := func(e entry) float64 { return float64(e.Score) }
+	owner := WithOwner(func(e entry) string { return e.User })
+	rank := NewQueueRank[entry](repo, 2, score, owner)
+	assert.Equal(t, len(rank.Items()), 1)
+	assert.Equal(t, repo.Size(), 1)
+
+	item := rank.Add(entry{"u1", 6})
+	assert.Equal(t, item.Best, false)
+	assert.Equal(t, item.Item, entry{"u1", 7})
+	item = rank.Add(entry{"u1", 9})
+	assert.Equal(t, item.Best, true)
+	assert.Equal(t, item.Index, 0)
+	assert.Equal(t, len(rank.Items()), 1)
+
+	rank.Add(entry{"u2", 8})
+	item = rank.Add(entry{"u3", 1})
+	assert.Equal(t, item.Index, -1)
+	assert.Equal(t, len(rank.Items()), 2)
+	assert.Equal(t, repo.Size(), 2)
+
+	// the evicted owner may enter again
+	item = rank.Add(entry{"u3", 10})
+	assert.Equal(t, item.Index, 0)
+	assert.Equal(t, rank.Items()[1].Item, entry{"u1", 9})
+}
+
+func TestQueueRankLatest(t *testing.T) {
+	type entry struct {
+		User  string
+		Score int
+	}
+	repo := NewMemRepo[entry]("test")
+	defer repo.Stop()
+	score := func(e entry) float64 { return float64(e.Score) }
+	rank := NewQueueRank[entry](repo, 10, score,
+		WithOwner(func(e entry) string { return e.User }), WithLatest[entry]())
+
+	rank.Add(entry{"u1", 9})
+	rank.Add(entry{"u2", 5})
+	item := rank.Add(entry{"u1", 3})
+	assert.Equal(t, item.Index, 1)
+	assert.Equal(t, item.Best, false)
+	own, ok := rank.RankOf("u1")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, own.Item, entry{"u1", 3})
+	assert.Equal(t, repo.Size(), 2)
+}
+
+func TestRankRemoveBan(t *testing.T) {
+	type entry struct {
+		User  string
+		Score int
+	}
+	repo := NewMemRepo[entry]("test")
+	defer repo.Stop()
+	bans := NewMemRepo[RankBan]("bans")
+	defer bans.Stop()
+	score := func(e entry) float64 { return float64(e.Score) }
+	key := func(e entry) string { return "k" }
+	rank := NewKeyedRank[entry](repo, 10, score, key,
+		WithOwner(func(e entry) string { return e.User }), WithBans[entry](bans))
+
+	rank.Add(entry{"u1", 9}, "k", "l")
+	rank.Add(entry{"u2", 5}, "k", "l")
+	item := rank.Add(entry{"u3", 7}, "k")[0]
+
+	removed, ok := rank.Rank("k").Remove(item.ID)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, removed.Index, 1)
+	assert.Equal(t, removed.Item, entry{"u3", 7})
+	_, ok = rank.Rank("k").Remove(item.ID)
+	assert.Equal(t, ok, false)
+	assert.Equal(t, rank.Rank("k").Len(), 2)
+
+	assert.Equal(t, len(rank.Ban("u1")), 2)
+	assert.Equal(t, rank.Rank("k").Len(), 1)
+	assert.Equal(t, rank.Add(entry{"u1", 10}, "m")[0].Index, -1)
+	assert.Equal(t, rank.Rank("m").Len(), 0)
+
+	// bans hold after a restart
+	reloaded := NewKeyedRank[entry](repo, 10, score, key,
+		WithOwner(func(e entry) string { return e.User }), WithBans[entry](bans))
+	assert.Equal(t, reloaded.Add(entry{"u1", 10}, "k")[0].Index, -1)
+	assert.Equal(t, reloaded.Unban("u1"), true)
+	assert.Equal(t, reloaded.Unban("u1"), false)
+	assert.Equal(t, reloaded.Add(entry{"u1", 10}, "k")[0].Index, 0)
+}
+
+func TestRankList(t *testing.T) {
+	list := newRankList[int]()
+	var nodes []*rankNode[int]
+	for i := 0; i < 2000; i++ {
+		n := &rankNode[int]{score: float64(rand.IntN(100)), item: i}
+		list.insert(n)
+		nodes = append(nodes, n)
+		if i%3 == 0 {
+			victim := rand.IntN(len(nodes))
+			list.remove(nodes[victim])
+			nodes = slices.Delete(nodes, victim, victim+1)
+		}
+	}
+	slices.SortStableFunc(nodes, func(a, b *rankNode[int]) int {
+		return cmp.Compare(b.score, a.score)
+	})
+	assert.Equal(t, list.length, len(nodes))
+	for i, n := range nodes {
+		at, ok := list.at(i)
+		assert.Equal(t, ok, true)
+		assert.Equal(t, at, n)
+		assert.Equal(t, list.indexOf(n), i)
+	}
+	_, ok := list.at(len(nodes))
+	assert.Equal(t, ok, false)
+}
+
+// heapSortRank is the former QueueRank, a min heap copied and sorted to find
+// the index of a new item.
+type heapSortRank struct {
+	capacity int
+	heap     intHeap
+}
+
+type intHeap []float64
+
+func (h intHeap) Len() int           { return len(h) }
+func (h intHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h intHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *intHeap) Push(x any)        { *h = append(*h, x.(float64)) }
+func (h *intHeap) Pop() any {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
+
+func (r *heapSortRank) add(score float64) int {
+	heap.Push(&r.heap, score)
+	if r.heap.Len() > r.capacity {
+		heap.Pop(&r.heap)
+	}
+	snapshot := slices.Clone(r.heap)
+	sort.Sort(sort.Reverse(sort.Float64Slice(snapshot)))
+	return sort.Search(len(snapshot), func(i int) bool { return snapshot[i] <= score })
+}
+
+// BenchmarkQueueRankAdd adds to a full rank of 10k items.
+func BenchmarkQueueRankAdd(b *testing.B) {
+	const capacity = 10000
+	b.Run("heap+sort", func(b *testing.B) {
+		r := &heapSortRank{capacity: capacity}
+		for i := 0; i < capacity; i++ {
+			r.add(rand.Float64())
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			r.add(rand.Float64())
+		}
+	})
+	b.Run("skiplist", func(b *testing.B) {
+		repo := NewMemRepo[float64]("bench")
+		defer repo.Stop()
+		r := NewQueueRank[float64](repo, capacity, func(v float64) float64 { return v })
+		for i := 0; i < capacity; i++ {
+			r.Add(rand.Float64())
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			r.Add(rand.Float64())
+		}
+	})
+}
+
+// BenchmarkQueueRankAt reads a random index of a rank of 10k items.
+func BenchmarkQueueRankAt(b *testing.B) {
+	const capacity = 10000
+	b.Run("heap+sort", func(b *testing.B) {
+		r := &heapSortRank{capacity: capacity}
+		for i := 0; i < capacity; i++ {
+			r.add(rand.Float64())
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			snapshot := slices.Clone(r.heap)
+			sort.Sort(sort.Reverse(sort.Float64Slice(snapshot)))
+			_ = snapshot[rand.IntN(capacity)]
+		}
+	})
+	b.Run("skiplist", func(b *testing.B) {
+		repo := NewMemRepo[float64]("bench")
+		defer repo.Stop()
+		r := NewQueueRank[float64](repo, capacity, func(v float64) float64 { return v })
+		for i := 0; i < capacity; i++ {
+			r.Add(rand.Float64())
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			r.At(rand.IntN(capacity))
+		}
+	})
+}
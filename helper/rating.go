@@ -0,0 +1,68 @@
+package helper
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// InitialRating and InitialDeviation rate a player without games.
+	InitialRating    = 1500.0
+	InitialDeviation = 350.0
+	// minDeviation keeps ratings of very active players responsive.
+	minDeviation = 30.0
+	// decayPerDay grows the deviation of an inactive player, so that a
+	// deviation of 50 returns to InitialDeviation after about 100 days.
+	decayPerDay = 34.6
+
+	glickoQ = math.Ln10 / 400
+)
+
+// Rating is a Glicko-1 skill rating: the player is Rating ± 2 Deviation with
+// 95% confidence as of Last.
+type Rating struct {
+	Rating    float64   `json:"rating,omitempty"`
+	Deviation float64   `json:"deviation,omitempty"`
+	Last      time.Time `json:"last,omitempty"`
+}
+
+// NewRating returns the rating of a new player.
+func NewRating() Rating {
+	return Rating{Rating: InitialRating, Deviation: InitialDeviation}
+}
+
+// Decayed returns the rating at now, less certain the longer the player has
+// been inactive.
+func (r Rating) Decayed(now time.Time) Rating {
+	if r.Last.IsZero() || !now.After(r.Last) {
+		return r
+	}
+	days := now.Sub(r.Last).Hours() / 24
+	r.Deviation = min(math.Sqrt(r.Deviation*r.Deviation+decayPerDay*decayPerDay*days), InitialDeviation)
+	return r
+}
+
+// Expected returns the chance of r to beat an opponent rated opponent ±
+// deviation.
+func (r Rating) Expected(opponent, deviation float64) float64 {
+	return 1 / (1 + math.Pow(10, -glickoG(deviation)*(r.Rating-opponent)/400))
+}
+
+// Play returns the rating after a game at now against an opponent rated
+// opponent ± deviation, where score is 1 for a win and 0 for a loss.
+func (r Rating) Play(opponent, deviation, score float64, now time.Time) Rating {
+	r = r.Decayed(now)
+	g := glickoG(deviation)
+	e := r.Expected(opponent, deviation)
+	d2 := 1 / (glickoQ * glickoQ * g * g * e * (1 - e))
+	precision := 1/(r.Deviation*r.Deviation) + 1/d2
+	return Rating{
+		Rating:    r.Rating + glickoQ/precision*g*(score-e),
+		Deviation: max(math.Sqrt(1/precision), minDeviation),
+		Last:      now,
+	}
+}
+
+func glickoG(deviation float64) float64 {
+	return 1 / math.Sqrt(1+3*glickoQ*glickoQ*deviation*deviation/(math.Pi*math.Pi))
+}
@@ -0,0 +1,33 @@
+package helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestRating(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewRating()
+
+	won := r.Play(InitialRating, 50, 1, now)
+	lost := r.Play(InitialRating, 50, 0, now)
+	assert.Equal(t, won.Rating > InitialRating, true)
+	assert.Equal(t, lost.Rating < InitialRating, true)
+	assert.Equal(t, won.Deviation < InitialDeviation, true)
+	assert.Equal(t, won.Last, now)
+
+	// beating a stronger opponent gains more
+	upset := r.Play(InitialRating+400, 50, 1, now)
+	assert.Equal(t, upset.Rating > won.Rating, true)
+
+	for i := 0; i < 500; i++ {
+		won = won.Play(won.Rating, 50, float64(i%2), now)
+	}
+	assert.Equal(t, won.Deviation, minDeviation)
+
+	assert.Equal(t, won.Decayed(now).Deviation, minDeviation)
+	assert.Equal(t, won.Decayed(now.AddDate(0, 0, 1)).Deviation > minDeviation, true)
+	assert.Equal(t, won.Decayed(now.AddDate(1, 0, 0)).Deviation, InitialDeviation)
+}
@@ -1,13 +1,7 @@
 package helper
 
 import (
-	"encoding/json"
-	"errors"
-	"io"
-	"log"
-	"os"
-	"path/filepath"
-	"strconv"
+	"hash/fnv"
 	"sync"
 	"time"
 )
@@ -20,43 +14,250 @@ type RepoInfo interface {
 
 type FileRepoInfo interface {
 	DataSize() int64
+	// ShardDataSizes returns the DataSize of each file shard.
+	ShardDataSizes() []int64
+}
+
+type CacheRepoInfo interface {
+	CacheStats() (hits, misses int64)
 }
 
 type Repo[T any] interface {
 	Get(key string) (T, bool)
+	// Put stores value with the default ttl of the repo: the one set by
+	// WithTTL for MemRepo, none for FileRepo and SQLRepo.
 	Put(key string, value T) bool
+	// PutWithTTL stores value and removes it once ttl has passed. A ttl <= 0
+	// stores value without a ttl, so it never expires, on every backend.
+	PutWithTTL(key string, value T, ttl time.Duration) bool
+	// Expire resets the ttl of an existing key without touching its value. A
+	// ttl <= 0 removes the ttl, like PutWithTTL.
+	Expire(key string, ttl time.Duration) bool
 	Del(key string) bool
+	// Update atomically replaces the value of key with the result of f.
+	// f receives the current value and whether it exists, and returns the new
+	// value and whether it should be stored. Update returns the value held
+	// after the call and whether f's result was stored. The key keeps its ttl.
+	Update(key string, f func(old T, exists bool) (T, bool)) (T, bool)
 	Range(f func(key string, value T) bool)
 	Stop()
 }
 
+// ExpiryRepo is implemented by repos that report when a key expires, so that
+// a backup restores the ttl along with the value.
+type ExpiryRepo interface {
+	// ExpiresAt returns when key expires. It reports false if key does not
+	// exist or never expires.
+	ExpiresAt(key string) (time.Time, bool)
+}
+
+// Reserver is implemented by repos that can hold a key for a while before
+// its value is stored, see GenRandomRepoShortID.
+type Reserver interface {
+	// Reserve takes key for ttl unless it exists or is reserved already. A
+	// reservation holds no value: Get, Range, indexes, watchers and backups
+	// do not see it and nothing is written to disk. Storing or deleting key
+	// ends it, and a ttl <= 0 keeps it until then.
+	Reserve(key string, ttl time.Duration) bool
+}
+
 type RepoType string
 
 const (
 	Memory RepoType = "Memory"
 	File   RepoType = "File"
+	SQL    RepoType = "SQL"
 	None   RepoType = "None"
 )
 
+// RepoOption configures a FileRepo or SQLRepo.
+type RepoOption func(*repoConfig)
+
+type repoConfig struct {
+	botID       int64
+	codec       Codec
+	compression Compression
+	shards      int
+	keyring     *Keyring
+}
+
+func newRepoConfig(opts []RepoOption) repoConfig {
+	cfg := repoConfig{codec: JSONCodec, compression: NoCompression, shards: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithBotID sets the bot whose data the repo holds. Its ID is part of the
+// file names, so several bots can share a data directory.
+func WithBotID(id int64) RepoOption {
+	return func(cfg *repoConfig) {
+		cfg.botID = id
+	}
+}
+
+// WithCodec sets the codec of the FileRepo snapshot and journal, JSONCodec by
+// default. Files written with another codec are still read and rewritten
+// with c at the next compaction.
+func WithCodec(c Codec) RepoOption {
+	return func(cfg *repoConfig) {
+		cfg.codec = c
+	}
+}
+
+// WithCompression sets the compression of the FileRepo snapshot,
+// NoCompression by default. The journal is never compressed.
+func WithCompression(c Compression) RepoOption {
+	return func(cfg *repoConfig) {
+		cfg.compression = c
+	}
+}
+
+// WithShards splits the FileRepo keys by hash across n snapshot and journal
+// files, so that compaction only rewrites the files of changed keys. Changing
+// n moves the keys into the new files on the next start.
+func WithShards(n int) RepoOption {
+	return func(cfg *repoConfig) {
+		cfg.shards = max(n, 1)
+	}
+}
+
+// WithKeyring encrypts the FileRepo snapshot and journal with the current
+// key of k. Files written with an older key of k or without encryption are
+// still read and rewritten with the current key at the next compaction.
+func WithKeyring(k *Keyring) RepoOption {
+	return func(cfg *repoConfig) {
+		cfg.keyring = k
+	}
+}
+
+// keyLock serializes writers of the same key without a repo-wide lock.
+type keyLock struct {
+	stripes [64]sync.Mutex
+}
+
+func (l *keyLock) lock(key string) *sync.Mutex {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	mu := &l.stripes[h.Sum32()%uint32(len(l.stripes))]
+	mu.Lock()
+	return mu
+}
+
+// neverExpire is the expiration of MemRepo keys without a ttl.
+var neverExpire = time.Unix(1<<62, 0)
+
+// reservations are the keys taken by Reserve. They only live in memory: the
+// data directory is locked to one process, and a reservation lost in a
+// crash held no value.
+type reservations struct {
+	mu   sync.Mutex
+	keys map[string]time.Time
+}
+
+// take reserves key unless it is reserved, dropping the reservations whose
+// ttl has passed.
+func (r *reservations) take(key string, ttl time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for k, until := range r.keys {
+		if now.After(until) {
+			delete(r.keys, k)
+		}
+	}
+	if _, ok := r.keys[key]; ok {
+		return false
+	}
+	if r.keys == nil {
+		r.keys = make(map[string]time.Time)
+	}
+	until := neverExpire
+	if ttl > 0 {
+		until = now.Add(ttl)
+	}
+	r.keys[key] = until
+	return true
+}
+
+// release ends the reservation of key, if any.
+func (r *reservations) release(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, key)
+}
+
 type Item[T any] struct {
 	value      T
+	ttl        time.Duration
 	expiration time.Time
 }
 
+// TTLMode decides whether reading a MemRepo key extends its ttl.
+type TTLMode int
+
+const (
+	// SlidingTTL restarts the ttl of a key whenever it is read.
+	SlidingTTL TTLMode = iota
+	// AbsoluteTTL expires a key ttl after it was written.
+	AbsoluteTTL
+)
+
+// MemRepoOption configures a MemRepo.
+type MemRepoOption func(*memRepoConfig)
+
+type memRepoConfig struct {
+	ttl  time.Duration
+	mode TTLMode
+}
+
+// WithTTL sets the ttl of keys written without one, an hour by default.
+func WithTTL(ttl time.Duration) MemRepoOption {
+	return func(cfg *memRepoConfig) {
+		cfg.ttl = ttl
+	}
+}
+
+// WithTTLMode sets whether reads slide the ttl, SlidingTTL by default.
+func WithTTLMode(mode TTLMode) MemRepoOption {
+	return func(cfg *memRepoConfig) {
+		cfg.mode = mode
+	}
+}
+
+// MemRepo keeps every value in memory until its ttl has passed. Expirations
+// are kept in a heap, so the cleanup only visits keys that are due. Sliding
+// a ttl on read does not touch the heap: a key found alive when it is due is
+// scheduled again at its new expiration.
 type MemRepo[T any] struct {
 	name    string
 	data    sync.Map
+	locks   keyLock
+	reserve reservations
+	index   repoIndex[T]
+	watch   watchers[T]
+	expiry  *expiryHeap
 	cleanup *time.Ticker
 	ttl     time.Duration
+	mode    TTLMode
 	stop    chan struct{}
 }
 
-func NewMemRepo[T any](name string) *MemRepo[T] {
+func NewMemRepo[T any](name string, opts ...MemRepoOption) *MemRepo[T] {
+	cfg := memRepoConfig{ttl: time.Hour, mode: SlidingTTL}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	repo := &MemRepo[T]{
 		name:    name,
 		stop:    make(chan struct{}),
+		expiry:  newExpiryHeap(),
 		cleanup: time.NewTicker(1 * time.Second),
-		ttl:     time.Hour,
+		ttl:     cfg.ttl,
+		mode:    cfg.mode,
 	}
 
 	go repo.cleanupExpired()
@@ -67,17 +268,10 @@ func NewMemRepo[T any](name string) *MemRepo[T] {
 func (r *MemRepo[T]) cleanupExpired() {
 	for {
 		select {
-		case <-r.cleanup.C:
-			r.data.Range(func(key, value any) bool {
-				item, ok := value.(Item[T])
-				if !ok {
-					return true
-				}
-				if time.Now().After(item.expiration) {
-					r.Del(key.(string))
-				}
-				return true
-			})
+		case now := <-r.cleanup.C:
+			for _, key := range r.expiry.due(now) {
+				r.expire(key)
+			}
 		case <-r.stop:
 			return
 		}
@@ -87,6 +281,7 @@ func (r *MemRepo[T]) cleanupExpired() {
 func (r *MemRepo[T]) Stop() {
 	close(r.stop)
 	r.cleanup.Stop()
+	r.watch.close()
 }
 
 func (r *MemRepo[T]) Type() string {
@@ -97,35 +292,22 @@ func (r *MemRepo[T]) Name() string {
 	return r.name
 }
 
+// Size returns the number of keys that have not expired.
 func (r *MemRepo[T]) Size() int {
-	count := 0
-	r.data.Range(func(key, value any) bool {
-		item, ok := value.(Item[T])
-		if !ok {
-			return true
-		}
-		if time.Now().After(item.expiration) {
-			r.Del(key.(string))
-		} else {
-			count++
-		}
-		return true
-	})
-	return count
+	for _, key := range r.expiry.due(time.Now()) {
+		r.expire(key)
+	}
+	return r.expiry.len()
 }
 
 func (r *MemRepo[T]) Range(f func(key string, value T) bool) {
+	now := time.Now()
 	r.data.Range(func(k, v any) bool {
 		item, ok := v.(Item[T])
-		if !ok {
+		if !ok || now.After(item.expiration) {
 			return true
 		}
-		if time.Now().After(item.expiration) {
-			r.Del(k.(string))
-		} else {
-			return f(k.(string), item.value)
-		}
-		return true
+		return f(k.(string), item.value)
 	})
 }
 
@@ -143,226 +325,177 @@ func (r *MemRepo[T]) Get(key string) (T, bool) {
 	}
 
 	if time.Now().After(castedItem.expiration) {
-		r.Del(key)
+		r.expire(key)
 		var zero T
 		return zero, false
 	}
 
-	r.Put(key, castedItem.value)
+	if r.mode == SlidingTTL {
+		r.touch(key)
+	}
 
 	return castedItem.value, true
 }
 
-func (r *MemRepo[T]) Put(key string, value T) bool {
-	expirationTime := time.Now().Add(r.ttl)
+// touch slides the expiration of key without overwriting a value stored
+// concurrently by Put or Update. The heap entry is left as is and moved when
+// it becomes due.
+func (r *MemRepo[T]) touch(key string) {
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
 
-	r.data.Store(key, Item[T]{
-		value:      value,
-		expiration: expirationTime,
-	})
-
-	return true
+	if item, ok := r.load(key); ok && item.ttl > 0 {
+		item.expiration = time.Now().Add(item.ttl)
+		r.data.Store(key, item)
+	}
 }
 
-func (r *MemRepo[T]) Del(key string) bool {
-	_, exists := r.data.Load(key)
-	if exists {
-		r.data.Delete(key)
-		return true
-	}
-	return false
-}
-
-type FileRepo[T any] struct {
-	dir      string
-	name     string
-	filename string
-	data     *sync.Map
-	sticker  *time.Ticker
-	stop     chan struct{}
-}
-
-func NewFileRepo[T any](dir string, name string) *FileRepo[T] {
-	var (
-		fn       = name + "_" + strconv.FormatInt(BotID, 10) + "_data.json"
-		filename = filepath.Join(dir, fn)
-		file     *os.File
-		err      error
-		data     sync.Map
-	)
-
-	if _, err = os.Stat(filename); errors.Is(err, os.ErrNotExist) {
-		file, err = os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
-		if err != nil {
-			log.Panicf("Error create file repo: file error=%v", err)
-		}
-
-		encoder := json.NewEncoder(file)
-		if err = encoder.Encode(make(map[string]T)); err != nil {
-			log.Panicf("Error create file repo: encode {} in create file error=%v", err)
-		}
+// expire removes key if it is still expired once its lock is held.
+func (r *MemRepo[T]) expire(key string) {
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
 
-		if err = file.Sync(); err != nil {
-			log.Panicf("Error create file repo: sync file error=%v", err)
-		}
-
-		log.Printf("Create File(filename=%s) with {} success", filename)
-	} else {
-		file, err = os.OpenFile(filename, os.O_RDWR, 0666)
-		if err != nil {
-			log.Panicf("Error create file repo: file error=%v", err)
-		}
-
-		var temp map[string]T
-		decoder := json.NewDecoder(file)
-		if err = decoder.Decode(&temp); err != nil {
-			log.Panicf("Error create file repo: decode failed error=%v", err)
-		}
-
-		for key, value := range temp {
-			data.Store(key, value)
-		}
-		log.Printf("Load File(filename=%s) data success", filename)
+	v, ok := r.data.Load(key)
+	if !ok {
+		return
 	}
-
-	repo := &FileRepo[T]{
-		dir:      dir,
-		name:     name,
-		filename: fn,
-		data:     &data,
-		stop:     make(chan struct{}),
-		sticker:  time.NewTicker(5 * time.Second),
+	item, ok := v.(Item[T])
+	if ok && !time.Now().After(item.expiration) {
+		r.expiry.schedule(key, item.expiration)
+		return
 	}
-
-	go repo.loop()
-
-	return repo
+	r.data.Delete(key)
+	r.expiry.remove(key)
+	r.index.del(key)
+	r.watch.emit(Event[T]{Type: EventExpire, Key: key, Old: item.value, Exists: ok})
 }
 
-func (r *FileRepo[T]) loop() {
-	for {
-		select {
-		case <-r.sticker.C:
-			err := r.Sync()
-			if err != nil {
-				log.Printf("Sync repo into file failed %v", err)
-			}
-		case <-r.stop:
-			return
-		}
-	}
+func (r *MemRepo[T]) Put(key string, value T) bool {
+	return r.PutWithTTL(key, value, r.ttl)
 }
 
-func (r *FileRepo[T]) Sync() error {
-	temp, err := os.CreateTemp(r.dir, "repo_*.json")
-	if err != nil {
-		return err
-	}
-	defer temp.Close()
-	defer os.Remove(temp.Name())
-	encoder := json.NewEncoder(temp)
+func (r *MemRepo[T]) PutWithTTL(key string, value T, ttl time.Duration) bool {
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
 
-	tempMap := make(map[string]T)
-	r.data.Range(func(key, value any) bool {
-		strKey, ok := key.(string)
-		if !ok {
-			return true
-		}
-		tv, ok := value.(T)
-		if !ok {
-			return true
-		}
-		tempMap[strKey] = tv
-		return true
-	})
+	r.store(key, value, ttl)
+	return true
+}
 
-	if err = encoder.Encode(tempMap); err != nil {
-		return err
-	}
-	if err = temp.Sync(); err != nil {
-		return err
+func (r *MemRepo[T]) set(key string, value T, ttl time.Duration) {
+	expiration := neverExpire
+	if ttl > 0 {
+		expiration = time.Now().Add(ttl)
 	}
+	r.data.Store(key, Item[T]{
+		value:      value,
+		ttl:        ttl,
+		expiration: expiration,
+	})
+	r.expiry.schedule(key, expiration)
+}
 
-	dst, err := os.Create(filepath.Join(r.dir, r.filename))
-	if err != nil {
-		return err
+// store writes a new value of key; the caller holds the key lock.
+func (r *MemRepo[T]) store(key string, value T, ttl time.Duration) {
+	var old Item[T]
+	exists := false
+	if r.watch.active() {
+		old, exists = r.load(key)
 	}
-	defer dst.Close()
+	r.set(key, value, ttl)
+	r.reserve.release(key)
+	r.index.put(key, value)
+	r.watch.emit(Event[T]{Type: EventPut, Key: key, Old: old.value, Exists: exists, New: value})
+}
 
-	_, err = temp.Seek(0, io.SeekStart)
-	if err != nil {
-		return err
+func (r *MemRepo[T]) load(key string) (Item[T], bool) {
+	item, ok := r.data.Load(key)
+	if !ok {
+		return Item[T]{}, false
 	}
-
-	_, err = io.Copy(dst, temp)
-	if err != nil {
-		return err
+	castedItem, ok := item.(Item[T])
+	if !ok || time.Now().After(castedItem.expiration) {
+		return Item[T]{}, false
 	}
-
-	return nil
+	return castedItem, true
 }
 
-func (r *FileRepo[T]) Type() string {
-	return string(File)
+func (r *MemRepo[T]) ExpiresAt(key string) (time.Time, bool) {
+	item, ok := r.load(key)
+	return item.expiration, ok && item.ttl > 0
 }
 
-func (r *FileRepo[T]) Name() string {
-	return r.name
-}
+func (r *MemRepo[T]) Expire(key string, ttl time.Duration) bool {
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
 
-func (r *FileRepo[T]) DataSize() int64 {
-	fileInfo, err := os.Stat(filepath.Join(r.dir, r.filename))
-	if err != nil {
-		return 0
+	item, ok := r.load(key)
+	if !ok {
+		return false
 	}
-	return fileInfo.Size()
+	r.set(key, item.value, ttl)
+	return true
 }
 
-func (r *FileRepo[T]) Size() int {
-	count := 0
-	r.data.Range(func(_, _ any) bool {
-		count++
-		return true
-	})
-	return count
-}
+func (r *MemRepo[T]) Update(key string, f func(old T, exists bool) (T, bool)) (T, bool) {
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
 
-func (r *FileRepo[T]) Range(f func(key string, value T) bool) {
-	r.data.Range(func(k, v any) bool {
-		return f(k.(string), v.(T))
-	})
+	item, exists := r.load(key)
+	if !exists {
+		item.ttl = r.ttl
+	}
+
+	value, ok := f(item.value, exists)
+	if !ok {
+		return item.value, false
+	}
+	r.store(key, value, item.ttl)
+	return value, true
 }
 
-func (r *FileRepo[T]) Stop() {
-	close(r.stop)
-	err := r.Sync()
-	if err != nil {
-		log.Printf("Sync repo into file failed %v", err)
+func (r *MemRepo[T]) Del(key string) bool {
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
+
+	r.reserve.release(key)
+	v, exists := r.data.LoadAndDelete(key)
+	if !exists {
+		return false
 	}
-	if err != nil {
-		log.Printf("Sync repo into file failed %v", err)
+	r.expiry.remove(key)
+	r.index.del(key)
+	if item, ok := v.(Item[T]); ok {
+		e := Event[T]{Type: EventDel, Key: key, Old: item.value, Exists: true}
+		if time.Now().After(item.expiration) {
+			e.Type = EventExpire
+		}
+		r.watch.emit(e)
 	}
+	return true
 }
 
-func (r *FileRepo[T]) Get(key string) (T, bool) {
-	v, ok := r.data.Load(key)
-	if v != nil {
-		return v.(T), ok
+func (r *MemRepo[T]) Reserve(key string, ttl time.Duration) bool {
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
+
+	if _, ok := r.load(key); ok {
+		return false
 	}
-	var zero T
-	return zero, ok
+	return r.reserve.take(key, ttl)
 }
 
-func (r *FileRepo[T]) Put(key string, value T) bool {
-	r.data.Store(key, value)
-	return true
+func (r *MemRepo[T]) Watch(prefix string, f func(Event[T])) (cancel func()) {
+	return r.watch.watch(prefix, f)
 }
 
-func (r *FileRepo[T]) Del(key string) bool {
-	_, exists := r.data.Load(key)
-	if exists {
-		r.data.Delete(key)
-		return true
-	}
-	return false
+func (r *MemRepo[T]) AddIndex(name string, f IndexFunc[T]) {
+	r.index.add(name, f, r.Range)
+}
+
+func (r *MemRepo[T]) Query(index, value, cursor string, limit int) (Page[T], error) {
+	return r.index.query(index, value, cursor, limit, func(key string) (T, bool) {
+		item, ok := r.load(key)
+		return item.value, ok
+	})
 }
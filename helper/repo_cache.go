@@ -0,0 +1,299 @@
+package helper
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cacheMaxAge bounds how long a value read from the underlying repo is
+// served, since its ttl is unknown to the cache.
+const cacheMaxAge = time.Minute
+
+type cacheEntry[T any] struct {
+	key    string
+	value  T
+	expire time.Time
+}
+
+// CachedRepo is a read-through, write-through LRU cache in front of another
+// repo. Repos implementing Watcher invalidate cached keys written by others;
+// reads served from the cache do not reach the repo, so they do not slide
+// the ttl of a MemRepo.
+type CachedRepo[T any] struct {
+	repo    Repo[T]
+	size    int
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	// pending counts the put events of each key the cache wrote itself and
+	// has not seen come back from the repo yet.
+	pending map[string]int
+	locks   keyLock
+	hits    atomic.Int64
+	misses  atomic.Int64
+	cancel  func()
+}
+
+func NewCachedRepo[T any](repo Repo[T], size int) *CachedRepo[T] {
+	c := &CachedRepo[T]{
+		repo:    repo,
+		size:    max(size, 1),
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		pending: make(map[string]int),
+	}
+	if w, ok := repo.(Watcher[T]); ok {
+		c.cancel = w.Watch("", c.invalidate)
+	}
+	return c
+}
+
+// invalidate drops key unless the event is the echo of a put of the cache.
+// Events of a key arrive in write order, so once every own put has been
+// echoed, any further event comes from another writer.
+func (c *CachedRepo[T]) invalidate(e Event[T]) {
+	mu := c.locks.lock(e.Key)
+	defer mu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if n := c.pending[e.Key]; n > 0 && e.Type == EventPut {
+		if n == 1 {
+			delete(c.pending, e.Key)
+		} else {
+			c.pending[e.Key] = n - 1
+		}
+		return
+	}
+	c.remove(e.Key)
+}
+
+// set caches value and evicts the least recently used key if the cache is
+// full; the caller holds c.mu.
+func (c *CachedRepo[T]) set(key string, value T, expire time.Time) {
+	if el, ok := c.entries[key]; ok {
+		el.Value = cacheEntry[T]{key: key, value: value, expire: expire}
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(cacheEntry[T]{key: key, value: value, expire: expire})
+	if c.lru.Len() > c.size {
+		c.remove(c.lru.Back().Value.(cacheEntry[T]).key)
+	}
+}
+
+func (c *CachedRepo[T]) remove(key string) {
+	if el, ok := c.entries[key]; ok {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+	}
+}
+
+// expireAt returns until when a value written with ttl may be served. A
+// repo that does not report its changes may delete or expire the value
+// unseen, so its values are only served for cacheMaxAge, like those read
+// from it.
+func (c *CachedRepo[T]) expireAt(ttl time.Duration) time.Time {
+	var expire time.Time
+	if c.cancel == nil {
+		expire = time.Now().Add(cacheMaxAge)
+	}
+	if ttl > 0 && (expire.IsZero() || ttl < cacheMaxAge) {
+		expire = time.Now().Add(ttl)
+	}
+	return expire
+}
+
+// stored records a successful put of the cache; the caller holds the key lock.
+func (c *CachedRepo[T]) stored(key string, value T, expire time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel != nil {
+		c.pending[key]++
+	}
+	c.set(key, value, expire)
+}
+
+func (c *CachedRepo[T]) Get(key string) (T, bool) {
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(cacheEntry[T])
+		if entry.expire.IsZero() || time.Now().Before(entry.expire) {
+			c.lru.MoveToFront(el)
+			c.mu.Unlock()
+			c.hits.Add(1)
+			return entry.value, true
+		}
+		c.remove(key)
+	}
+	c.mu.Unlock()
+	c.misses.Add(1)
+
+	// Hold the key lock while loading, so an invalidation of a concurrent
+	// write cannot run before the loaded value is cached.
+	mu := c.locks.lock(key)
+	defer mu.Unlock()
+	value, ok := c.repo.Get(key)
+	if ok {
+		c.mu.Lock()
+		c.set(key, value, time.Now().Add(cacheMaxAge))
+		c.mu.Unlock()
+	}
+	return value, ok
+}
+
+func (c *CachedRepo[T]) Put(key string, value T) bool {
+	mu := c.locks.lock(key)
+	defer mu.Unlock()
+
+	if !c.repo.Put(key, value) {
+		return false
+	}
+	// a MemRepo puts with its default ttl, which its expire events cover
+	c.stored(key, value, c.expireAt(0))
+	return true
+}
+
+func (c *CachedRepo[T]) PutWithTTL(key string, value T, ttl time.Duration) bool {
+	mu := c.locks.lock(key)
+	defer mu.Unlock()
+
+	if !c.repo.PutWithTTL(key, value, ttl) {
+		return false
+	}
+	c.stored(key, value, c.expireAt(ttl))
+	return true
+}
+
+func (c *CachedRepo[T]) Expire(key string, ttl time.Duration) bool {
+	mu := c.locks.lock(key)
+	defer mu.Unlock()
+
+	ok := c.repo.Expire(key, ttl)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, cached := c.entries[key]; ok && cached {
+		entry := el.Value.(cacheEntry[T])
+		entry.expire = c.expireAt(ttl)
+		el.Value = entry
+	} else {
+		c.remove(key)
+	}
+	return ok
+}
+
+func (c *CachedRepo[T]) Update(key string, f func(old T, exists bool) (T, bool)) (T, bool) {
+	mu := c.locks.lock(key)
+	defer mu.Unlock()
+
+	value, ok := c.repo.Update(key, f)
+	if !ok {
+		return value, false
+	}
+	expire := time.Now().Add(cacheMaxAge)
+	c.mu.Lock()
+	if el, cached := c.entries[key]; cached {
+		expire = el.Value.(cacheEntry[T]).expire
+	}
+	c.mu.Unlock()
+	c.stored(key, value, expire)
+	return value, true
+}
+
+func (c *CachedRepo[T]) Del(key string) bool {
+	mu := c.locks.lock(key)
+	defer mu.Unlock()
+
+	ok := c.repo.Del(key)
+	c.mu.Lock()
+	c.remove(key)
+	c.mu.Unlock()
+	return ok
+}
+
+func (c *CachedRepo[T]) Reserve(key string, ttl time.Duration) bool {
+	if r, ok := c.repo.(Reserver); ok {
+		return r.Reserve(key, ttl)
+	}
+	return false
+}
+
+func (c *CachedRepo[T]) Range(f func(key string, value T) bool) {
+	c.repo.Range(f)
+}
+
+func (c *CachedRepo[T]) Stop() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.repo.Stop()
+}
+
+// CacheStats returns the number of reads served from and missing the cache.
+func (c *CachedRepo[T]) CacheStats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+func (c *CachedRepo[T]) Name() string {
+	if info, ok := c.repo.(RepoInfo); ok {
+		return info.Name()
+	}
+	return ""
+}
+
+func (c *CachedRepo[T]) Type() string {
+	if info, ok := c.repo.(RepoInfo); ok {
+		return info.Type()
+	}
+	return string(None)
+}
+
+func (c *CachedRepo[T]) Size() int {
+	if info, ok := c.repo.(RepoInfo); ok {
+		return info.Size()
+	}
+	return 0
+}
+
+func (c *CachedRepo[T]) DataSize() int64 {
+	if info, ok := c.repo.(FileRepoInfo); ok {
+		return info.DataSize()
+	}
+	return 0
+}
+
+func (c *CachedRepo[T]) ShardDataSizes() []int64 {
+	if info, ok := c.repo.(FileRepoInfo); ok {
+		return info.ShardDataSizes()
+	}
+	return nil
+}
+
+func (c *CachedRepo[T]) ExpiresAt(key string) (time.Time, bool) {
+	if e, ok := c.repo.(ExpiryRepo); ok {
+		return e.ExpiresAt(key)
+	}
+	return time.Time{}, false
+}
+
+func (c *CachedRepo[T]) Freeze() (unfreeze func()) {
+	if f, ok := c.repo.(Freezer); ok {
+		return f.Freeze()
+	}
+	return func() {}
+}
+
+func (c *CachedRepo[T]) AddIndex(name string, f IndexFunc[T]) {
+	if idx, ok := c.repo.(Indexer[T]); ok {
+		idx.AddIndex(name, f)
+	}
+}
+
+func (c *CachedRepo[T]) Query(index, value, cursor string, limit int) (Page[T], error) {
+	if idx, ok := c.repo.(Indexer[T]); ok {
+		return idx.Query(index, value, cursor, limit)
+	}
+	return Page[T]{}, ErrIndexNotFound
+}
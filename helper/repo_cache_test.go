@@ -0,0 +1,60 @@
+package helper
+
+import (
+	"github.com/go-playground/assert/v2"
+	"testing"
+	"time"
+)
+
+func TestCachedRepo(t *testing.T) {
+	mem := NewMemRepo[int]("test")
+	repo := NewCachedRepo[int](mem, 2)
+	defer repo.Stop()
+
+	repo.Put("a", 1)
+	repo.Put("b", 2)
+	value, _ := repo.Get("a")
+	assert.Equal(t, value, 1)
+	repo.Put("c", 3)
+	// b was the least recently used key
+	_, cached := repo.entries["b"]
+	assert.Equal(t, cached, false)
+	value, _ = repo.Get("b")
+	assert.Equal(t, value, 2)
+	hits, misses := repo.CacheStats()
+	assert.Equal(t, hits, int64(1))
+	assert.Equal(t, misses, int64(1))
+
+	testRepoUpdate(t, repo)
+
+	// writes to the underlying repo invalidate the cached key
+	repo.Put("d", 4)
+	mem.Put("d", 5)
+	deadline := time.Now().Add(time.Second)
+	for value, _ = repo.Get("d"); value != 5 && time.Now().Before(deadline); value, _ = repo.Get("d") {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, value, 5)
+
+	mem.Del("d")
+	time.Sleep(10 * time.Millisecond)
+	_, ok := repo.Get("d")
+	assert.Equal(t, ok, false)
+}
+
+func TestCachedRepoUnwatched(t *testing.T) {
+	sql := NewSQLRepo[int](t.TempDir(), "test")
+	repo := NewCachedRepo[int](sql, 4)
+	defer repo.Stop()
+
+	// values of a repo that does not report changes are served for a while only
+	repo.Put("a", 1)
+	entry := repo.entries["a"].Value.(cacheEntry[int])
+	assert.Equal(t, entry.expire.IsZero(), false)
+	assert.Equal(t, time.Until(entry.expire) <= cacheMaxAge, true)
+
+	repo.PutWithTTL("b", 2, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, ok := repo.Get("b")
+	assert.Equal(t, ok, false)
+}
@@ -0,0 +1,955 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type journalOp string
+
+const (
+	opPut    journalOp = "put"
+	opDel    journalOp = "del"
+	opExpire journalOp = "exp"
+)
+
+// journalEntry is one record of the FileRepo write-ahead journal. Schema is
+// the SchemaVersion of Value when it was written.
+type journalEntry[T any] struct {
+	Op     journalOp  `json:"op"`
+	Key    string     `json:"key"`
+	Schema int        `json:"schema,omitempty"`
+	Value  *T         `json:"value,omitempty"`
+	Expire *time.Time `json:"expire,omitempty"`
+}
+
+// A plain JSON journal holds one entry per line. Journals of other codecs or
+// encrypted journals start with a journalHeader line naming the codec and
+// key, followed by entries framed by their uvarint encoded length.
+type journalHeader struct {
+	Codec string `json:"codec"`
+	Key   string `json:"key,omitempty"`
+}
+
+const (
+	legacySnapshotFormat = 1
+	snapshotFormat       = 2
+)
+
+// snapshotHeader is the first line of a FileRepo snapshot. The rest of the
+// file is a snapshotBody encoded by Codec, compressed by Compression and
+// encrypted by the keyring key called Key if set. Every record in it has the
+// SchemaVersion given by Schema.
+type snapshotHeader struct {
+	Format      int    `json:"format"`
+	Codec       string `json:"codec"`
+	Compression string `json:"compression"`
+	Key         string `json:"key,omitempty"`
+	Schema      int    `json:"schema,omitempty"`
+}
+
+type snapshotBody[T any] struct {
+	Data    map[string]T         `json:"data"`
+	Expires map[string]time.Time `json:"expires,omitempty"`
+}
+
+// fileSnapshot is the layout of snapshots written before codecs could be
+// configured. Files written before expirations existed hold the bare data
+// map instead.
+type fileSnapshot[T any] struct {
+	Format  int                  `json:"format"`
+	Schema  int                  `json:"schema,omitempty"`
+	Data    map[string]T         `json:"data"`
+	Expires map[string]time.Time `json:"expires,omitempty"`
+}
+
+// FileRepo keeps every value in memory and persists it as a snapshot plus an
+// append-only journal, both encoded with the configured Codec. Each Put/Del
+// is fsynced to the journal before it is applied, and the journal is
+// periodically compacted into a new snapshot that replaces the old one with
+// an atomic rename. With WithShards the keys are split by hash across several
+// snapshot and journal files, and compaction only rewrites the shards that
+// changed.
+type FileRepo[T any] struct {
+	dir      string
+	name     string
+	prefix   string
+	shards   []*fileShard
+	obsolete []string
+	codec    Codec
+	compress Compression
+	keyring  *Keyring
+	data     *sync.Map
+	expires  *sync.Map
+	locks    keyLock
+	reserve  reservations
+	index    repoIndex[T]
+	watch    watchers[T]
+	compact  sync.RWMutex
+	sticker  *time.Ticker
+	stop     chan struct{}
+}
+
+// fileShard is the snapshot and journal holding the keys of one shard.
+type fileShard struct {
+	filename string
+	journal  string
+	jmu      sync.Mutex
+	jfile    *os.File
+	// dirty is set by writers and cleared once the shard is compacted.
+	dirty atomic.Bool
+}
+
+func NewFileRepo[T any](dir string, name string, opts ...RepoOption) *FileRepo[T] {
+	repo, err := OpenFileRepo[T](dir, name, opts...)
+	if err != nil {
+		log.Panicf("Error create file repo: %v", err)
+	}
+	return repo
+}
+
+// OpenFileRepo is NewFileRepo returning an error instead of panicking, e.g.
+// ErrKeyMissing or ErrKeyWrong when the files cannot be decrypted.
+func OpenFileRepo[T any](dir string, name string, opts ...RepoOption) (*FileRepo[T], error) {
+	var (
+		cfg = newRepoConfig(opts)
+		err error
+	)
+
+	repo := &FileRepo[T]{
+		dir:      dir,
+		name:     name,
+		prefix:   name + "_" + strconv.FormatInt(cfg.botID, 10),
+		codec:    cfg.codec,
+		compress: cfg.compression,
+		keyring:  cfg.keyring,
+		data:     &sync.Map{},
+		expires:  &sync.Map{},
+		stop:     make(chan struct{}),
+	}
+	for i := 0; i < cfg.shards; i++ {
+		prefix := repo.shardPrefix(i, cfg.shards)
+		repo.shards = append(repo.shards, &fileShard{
+			filename: prefix + "_data.json",
+			journal:  prefix + "_data.journal",
+		})
+	}
+
+	if err = repo.load(); err != nil {
+		return nil, fmt.Errorf("load repo %s: %w", name, err)
+	}
+
+	for _, shard := range repo.shards {
+		shard.jfile, err = os.OpenFile(repo.path(shard.journal), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			repo.closeJournals()
+			return nil, fmt.Errorf("open journal of repo %s: %w", name, err)
+		}
+		shard.dirty.Store(true)
+	}
+
+	// Fold the replayed journal into a fresh snapshot, which also drops a
+	// partially written trailing record left by a crash and rewrites files
+	// of another codec, compression, key or shard count with the configured
+	// ones.
+	if err = repo.Sync(); err != nil {
+		repo.closeJournals()
+		return nil, fmt.Errorf("compact repo %s: %w", name, err)
+	}
+	for _, fn := range repo.obsolete {
+		if err = os.Remove(repo.path(fn)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Remove obsolete File(filename=%s) failed: %v", fn, err)
+		}
+	}
+	repo.obsolete = nil
+
+	repo.sticker = time.NewTicker(time.Minute)
+	go repo.loop()
+
+	return repo, nil
+}
+
+func (r *FileRepo[T]) closeJournals() {
+	for _, shard := range r.shards {
+		if shard.jfile != nil {
+			if err := shard.jfile.Close(); err != nil {
+				log.Printf("Close repo journal failed %v", err)
+			}
+		}
+	}
+}
+
+// framed reports whether journal entries are written as length framed
+// records after a journalHeader instead of JSON lines.
+func (r *FileRepo[T]) framed() bool {
+	return r.codec != JSONCodec || r.keyring != nil
+}
+
+func (r *FileRepo[T]) path(fn string) string {
+	return filepath.Join(r.dir, fn)
+}
+
+// shardPrefix names the files of shard i out of n. A single shard keeps the
+// file names of an unsharded repo.
+func (r *FileRepo[T]) shardPrefix(i, n int) string {
+	if n == 1 {
+		return r.prefix
+	}
+	return r.prefix + "_" + strconv.Itoa(i)
+}
+
+func (r *FileRepo[T]) shardIndex(key string) int {
+	if len(r.shards) == 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(r.shards)))
+}
+
+func (r *FileRepo[T]) shard(key string) *fileShard {
+	return r.shards[r.shardIndex(key)]
+}
+
+// layouts returns the file prefixes found on disk that do not belong to the
+// configured shards, e.g. after the shard count was changed.
+func (r *FileRepo[T]) layouts() ([]string, error) {
+	current := make(map[string]bool, len(r.shards))
+	for i := range r.shards {
+		current[r.shardPrefix(i, len(r.shards))] = true
+	}
+	var found []string
+	for _, suffix := range []string{"_data.json", "_data.journal"} {
+		if _, err := os.Stat(r.path(r.prefix + suffix)); err == nil {
+			found = append(found, r.prefix)
+		}
+		matches, err := filepath.Glob(r.path(r.prefix + "_*" + suffix))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			prefix := strings.TrimSuffix(filepath.Base(match), suffix)
+			if _, err = strconv.Atoi(strings.TrimPrefix(prefix, r.prefix+"_")); err == nil {
+				found = append(found, prefix)
+			}
+		}
+	}
+	slices.Sort(found)
+	found = slices.Compact(found)
+	return slices.DeleteFunc(found, func(prefix string) bool {
+		return current[prefix]
+	}), nil
+}
+
+// load reads the snapshots and replays the journals on top of them. Files of
+// another shard layout are read first and removed after the first compaction.
+func (r *FileRepo[T]) load() error {
+	others, err := r.layouts()
+	if err != nil {
+		return err
+	}
+	for _, prefix := range others {
+		if err = r.loadFiles(prefix+"_data.json", prefix+"_data.journal"); err != nil {
+			return err
+		}
+		r.obsolete = append(r.obsolete, prefix+"_data.json", prefix+"_data.journal")
+	}
+	for _, shard := range r.shards {
+		if err = r.loadFiles(shard.filename, shard.journal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *FileRepo[T]) loadFiles(snapshotFile, journalFile string) error {
+	filename := r.path(snapshotFile)
+	raw, err := os.ReadFile(filename)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		log.Printf("Create File(filename=%s) with {} success", filename)
+	case err != nil:
+		return err
+	default:
+		snapshot, err := decodeSnapshot[T](raw, r.keyring)
+		if err != nil {
+			return fmt.Errorf("decode snapshot %s: %w", filename, err)
+		}
+		for key, value := range snapshot.Data {
+			r.data.Store(key, value)
+		}
+		for key, expire := range snapshot.Expires {
+			r.expires.Store(key, expire)
+		}
+		log.Printf("Load File(filename=%s) data success", filename)
+	}
+
+	journal := r.path(journalFile)
+	raw, err = os.ReadFile(journal)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	replayed, err := readJournal(raw, r.keyring, func(entry journalEntry[T]) {
+		switch entry.Op {
+		case opPut:
+			if entry.Value != nil {
+				r.data.Store(entry.Key, *entry.Value)
+				r.setExpire(entry.Key, entry.Expire)
+			}
+		case opExpire:
+			r.setExpire(entry.Key, entry.Expire)
+		case opDel:
+			r.data.Delete(entry.Key)
+			r.expires.Delete(entry.Key)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("decode journal %s: %w", journal, err)
+	}
+	if replayed > 0 {
+		log.Printf("Replay Journal(filename=%s) %d entries success", journal, replayed)
+	}
+	return nil
+}
+
+// readJournal calls f for every entry of a journal written with any codec.
+// It stops at a partially written trailing entry.
+func readJournal[T any](raw []byte, keyring *Keyring, f func(journalEntry[T])) (int, error) {
+	var (
+		header   journalHeader
+		codec    = JSONCodec
+		framed   = false
+		replayed = 0
+	)
+	if line, rest, ok := bytes.Cut(raw, []byte{'\n'}); ok {
+		if json.Unmarshal(line, &header) == nil && header.Codec != "" {
+			if codec, ok = codecs[header.Codec]; !ok {
+				return 0, errors.New("unknown codec " + header.Codec)
+			}
+			framed = true
+			raw = rest
+		}
+	}
+
+	if !framed {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		for {
+			var stored journalEntry[json.RawMessage]
+			if err := decoder.Decode(&stored); err != nil {
+				if !errors.Is(err, io.EOF) {
+					log.Printf("Journal truncated after %d entries: %v", replayed, err)
+				}
+				return replayed, nil
+			}
+			entry, err := migrateEntry[T](stored)
+			if err != nil {
+				return replayed, err
+			}
+			f(entry)
+			replayed++
+		}
+	}
+
+	for len(raw) > 0 {
+		size, n := binary.Uvarint(raw)
+		if n <= 0 || uint64(len(raw)-n) < size {
+			log.Printf("Journal truncated after %d entries", replayed)
+			break
+		}
+		frame := raw[n : n+int(size)]
+		raw = raw[n+int(size):]
+
+		var err error
+		if header.Key != "" {
+			if frame, err = keyring.open(header.Key, frame); err != nil {
+				return replayed, err
+			}
+		}
+		entry, err := decodeEntry[T](codec, frame)
+		if err != nil {
+			return replayed, err
+		}
+		f(entry)
+		replayed++
+	}
+	return replayed, nil
+}
+
+// decodeEntry decodes one journal frame, migrating its value if it was
+// written with an older schema version.
+func decodeEntry[T any](codec Codec, frame []byte) (journalEntry[T], error) {
+	var (
+		entry journalEntry[T]
+		probe struct {
+			Schema int `json:"schema,omitempty"`
+		}
+	)
+	if err := codec.Unmarshal(frame, &probe); err != nil {
+		return entry, err
+	}
+	if probe.Schema == SchemaVersion[T]() {
+		err := codec.Unmarshal(frame, &entry)
+		return entry, err
+	}
+
+	var untyped journalEntry[any]
+	if err := decodeUntyped(codec, frame, &untyped); err != nil {
+		return entry, err
+	}
+	stored := journalEntry[json.RawMessage]{Op: untyped.Op, Key: untyped.Key, Schema: untyped.Schema, Expire: untyped.Expire}
+	if untyped.Value != nil {
+		record, err := json.Marshal(*untyped.Value)
+		if err != nil {
+			return entry, err
+		}
+		stored.Value = (*json.RawMessage)(&record)
+	}
+	return migrateEntry[T](stored)
+}
+
+func migrateEntry[T any](stored journalEntry[json.RawMessage]) (journalEntry[T], error) {
+	entry := journalEntry[T]{Op: stored.Op, Key: stored.Key, Schema: SchemaVersion[T](), Expire: stored.Expire}
+	if stored.Value != nil {
+		value, err := decodeRecord[T](stored.Schema, *stored.Value)
+		if err != nil {
+			return entry, errors.New("key " + stored.Key + ": " + err.Error())
+		}
+		entry.Value = &value
+	}
+	return entry, nil
+}
+
+func decodeSnapshot[T any](raw []byte, keyring *Keyring) (fileSnapshot[T], error) {
+	var (
+		header snapshotHeader
+		probe  struct {
+			Format int `json:"format"`
+		}
+		stored   fileSnapshot[json.RawMessage]
+		snapshot = fileSnapshot[T]{Format: legacySnapshotFormat, Schema: SchemaVersion[T]()}
+	)
+	if line, body, ok := bytes.Cut(raw, []byte{'\n'}); ok && json.Unmarshal(line, &header) == nil && header.Format == snapshotFormat {
+		return decodeSnapshotBody[T](header, body, keyring)
+	}
+	if err := json.Unmarshal(raw, &probe); err == nil && probe.Format == legacySnapshotFormat {
+		if err = json.Unmarshal(raw, &stored); err != nil {
+			return snapshot, err
+		}
+	} else if err = json.Unmarshal(raw, &stored.Data); err != nil {
+		return snapshot, err
+	}
+
+	snapshot.Data = make(map[string]T, len(stored.Data))
+	snapshot.Expires = stored.Expires
+	for key, record := range stored.Data {
+		value, err := decodeRecord[T](stored.Schema, record)
+		if err != nil {
+			return snapshot, errors.New("key " + key + ": " + err.Error())
+		}
+		snapshot.Data[key] = value
+	}
+	return snapshot, nil
+}
+
+func decodeSnapshotBody[T any](header snapshotHeader, body []byte, keyring *Keyring) (fileSnapshot[T], error) {
+	snapshot := fileSnapshot[T]{Format: header.Format, Schema: SchemaVersion[T]()}
+	if header.Key != "" {
+		var err error
+		if body, err = keyring.open(header.Key, body); err != nil {
+			return snapshot, err
+		}
+	}
+	codec, ok := codecs[header.Codec]
+	if !ok {
+		return snapshot, errors.New("unknown codec " + header.Codec)
+	}
+	compression, ok := compressions[header.Compression]
+	if !ok {
+		return snapshot, errors.New("unknown compression " + header.Compression)
+	}
+	reader, err := compression.Reader(bytes.NewReader(body))
+	if err != nil {
+		return snapshot, err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return snapshot, err
+	}
+
+	if header.Schema == snapshot.Schema {
+		var stored snapshotBody[T]
+		err = codec.Unmarshal(data, &stored)
+		snapshot.Data, snapshot.Expires = stored.Data, stored.Expires
+		return snapshot, err
+	}
+
+	var untyped snapshotBody[any]
+	if err = decodeUntyped(codec, data, &untyped); err != nil {
+		return snapshot, err
+	}
+	snapshot.Data = make(map[string]T, len(untyped.Data))
+	snapshot.Expires = untyped.Expires
+	for key, v := range untyped.Data {
+		record, err := json.Marshal(v)
+		if err == nil {
+			snapshot.Data[key], err = decodeRecord[T](header.Schema, record)
+		}
+		if err != nil {
+			return snapshot, errors.New("key " + key + ": " + err.Error())
+		}
+	}
+	return snapshot, nil
+}
+
+func (r *FileRepo[T]) setExpire(key string, expire *time.Time) {
+	if expire == nil {
+		r.expires.Delete(key)
+	} else {
+		r.expires.Store(key, *expire)
+	}
+}
+
+func (r *FileRepo[T]) expire(key string) *time.Time {
+	if v, ok := r.expires.Load(key); ok {
+		expire := v.(time.Time)
+		return &expire
+	}
+	return nil
+}
+
+func (r *FileRepo[T]) expired(key string, now time.Time) bool {
+	if v, ok := r.expires.Load(key); ok {
+		return now.After(v.(time.Time))
+	}
+	return false
+}
+
+// append writes entry to the journal and fsyncs it.
+func (r *FileRepo[T]) append(entry journalEntry[T]) error {
+	data, err := r.codec.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	var record []byte
+	if !r.framed() {
+		record = append(data, '\n')
+	} else {
+		if r.keyring != nil {
+			if data, err = r.keyring.seal(data); err != nil {
+				return err
+			}
+		}
+		record = append(binary.AppendUvarint(nil, uint64(len(data))), data...)
+	}
+
+	shard := r.shard(entry.Key)
+	shard.jmu.Lock()
+	defer shard.jmu.Unlock()
+
+	if _, err = shard.jfile.Write(record); err != nil {
+		return err
+	}
+	shard.dirty.Store(true)
+	return shard.jfile.Sync()
+}
+
+func (r *FileRepo[T]) loop() {
+	for {
+		select {
+		case <-r.sticker.C:
+			err := r.Sync()
+			if err != nil {
+				log.Printf("Sync repo into file failed %v", err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Sync compacts the journals into new snapshots and drops expired keys. Only
+// shards written to or holding expired keys since the last Sync are
+// rewritten. Each snapshot is written to a temp file, fsynced and renamed
+// over the old one, so a crash leaves either the old snapshot plus journal
+// or the new snapshot on disk.
+func (r *FileRepo[T]) Sync() error {
+	r.compact.Lock()
+	defer r.compact.Unlock()
+
+	now := time.Now()
+	snapshots := make([]snapshotBody[T], len(r.shards))
+	for i := range snapshots {
+		snapshots[i] = snapshotBody[T]{
+			Data:    make(map[string]T),
+			Expires: make(map[string]time.Time),
+		}
+	}
+	r.data.Range(func(key, value any) bool {
+		strKey, ok := key.(string)
+		if !ok {
+			return true
+		}
+		tv, ok := value.(T)
+		if !ok {
+			return true
+		}
+		i := r.shardIndex(strKey)
+		if r.expired(strKey, now) {
+			r.data.Delete(strKey)
+			r.expires.Delete(strKey)
+			r.index.del(strKey)
+			r.shards[i].dirty.Store(true)
+			r.watch.emit(Event[T]{Type: EventExpire, Key: strKey, Old: tv, Exists: true})
+			return true
+		}
+		snapshots[i].Data[strKey] = tv
+		if expire := r.expire(strKey); expire != nil {
+			snapshots[i].Expires[strKey] = *expire
+		}
+		return true
+	})
+
+	for i, shard := range r.shards {
+		if !shard.dirty.Load() {
+			continue
+		}
+		if err := r.syncShard(shard, snapshots[i]); err != nil {
+			return err
+		}
+		shard.dirty.Store(false)
+	}
+	return nil
+}
+
+func (r *FileRepo[T]) syncShard(shard *fileShard, snapshot snapshotBody[T]) error {
+	dir := r.dir
+	if dir == "" {
+		dir = "."
+	}
+	temp, err := os.CreateTemp(dir, "repo_*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	if err = r.writeSnapshot(temp, snapshot); err != nil {
+		return err
+	}
+	if err = temp.Sync(); err != nil {
+		return err
+	}
+	if err = temp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(temp.Name(), r.path(shard.filename)); err != nil {
+		return err
+	}
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+
+	shard.jmu.Lock()
+	defer shard.jmu.Unlock()
+	if err = shard.jfile.Truncate(0); err != nil {
+		return err
+	}
+	if r.framed() {
+		header := journalHeader{Codec: r.codec.Name()}
+		if r.keyring != nil {
+			header.Key = r.keyring.id()
+		}
+		line, err := json.Marshal(header)
+		if err != nil {
+			return err
+		}
+		if _, err = shard.jfile.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return shard.jfile.Sync()
+}
+
+func (r *FileRepo[T]) writeSnapshot(w io.Writer, snapshot snapshotBody[T]) error {
+	header := snapshotHeader{
+		Format:      snapshotFormat,
+		Codec:       r.codec.Name(),
+		Compression: r.compress.Name(),
+		Schema:      SchemaVersion[T](),
+	}
+	if r.keyring != nil {
+		header.Key = r.keyring.id()
+	}
+	line, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	data, err := r.codec.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	// The body is compressed before it is encrypted, as ciphertext does not
+	// compress.
+	var body bytes.Buffer
+	out := w
+	if r.keyring != nil {
+		out = &body
+	}
+	zw, err := r.compress.Writer(out)
+	if err != nil {
+		return err
+	}
+	if _, err = zw.Write(data); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	if r.keyring != nil {
+		sealed, err := r.keyring.seal(body.Bytes())
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(sealed)
+		return err
+	}
+	return nil
+}
+
+// Freeze blocks writers until unfreeze is called.
+func (r *FileRepo[T]) Freeze() (unfreeze func()) {
+	r.compact.Lock()
+	return r.compact.Unlock
+}
+
+func (r *FileRepo[T]) Type() string {
+	return string(File)
+}
+
+func (r *FileRepo[T]) Name() string {
+	return r.name
+}
+
+func (r *FileRepo[T]) DataSize() int64 {
+	var size int64
+	for _, shard := range r.ShardDataSizes() {
+		size += shard
+	}
+	return size
+}
+
+// ShardDataSizes returns the size of the snapshot plus journal of each shard.
+func (r *FileRepo[T]) ShardDataSizes() []int64 {
+	sizes := make([]int64, len(r.shards))
+	for i, shard := range r.shards {
+		for _, fn := range []string{shard.filename, shard.journal} {
+			if fileInfo, err := os.Stat(r.path(fn)); err == nil {
+				sizes[i] += fileInfo.Size()
+			}
+		}
+	}
+	return sizes
+}
+
+func (r *FileRepo[T]) Size() int {
+	count := 0
+	now := time.Now()
+	r.data.Range(func(k, _ any) bool {
+		if !r.expired(k.(string), now) {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+func (r *FileRepo[T]) Range(f func(key string, value T) bool) {
+	now := time.Now()
+	r.data.Range(func(k, v any) bool {
+		if r.expired(k.(string), now) {
+			return true
+		}
+		return f(k.(string), v.(T))
+	})
+}
+
+func (r *FileRepo[T]) Stop() {
+	close(r.stop)
+	r.sticker.Stop()
+	if err := r.Sync(); err != nil {
+		log.Printf("Sync repo into file failed %v", err)
+	}
+	r.watch.close()
+	for _, shard := range r.shards {
+		if err := shard.jfile.Close(); err != nil {
+			log.Printf("Close repo journal failed %v", err)
+		}
+	}
+}
+
+func (r *FileRepo[T]) Get(key string) (T, bool) {
+	var zero T
+	v, ok := r.data.Load(key)
+	if !ok || r.expired(key, time.Now()) {
+		return zero, false
+	}
+	return v.(T), true
+}
+
+func (r *FileRepo[T]) Put(key string, value T) bool {
+	return r.PutWithTTL(key, value, 0)
+}
+
+func (r *FileRepo[T]) PutWithTTL(key string, value T, ttl time.Duration) bool {
+	r.compact.RLock()
+	defer r.compact.RUnlock()
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
+
+	var expire *time.Time
+	if ttl > 0 {
+		at := time.Now().Add(ttl)
+		expire = &at
+	}
+	return r.store(key, value, expire)
+}
+
+// store journals and applies a put; the caller holds the key lock.
+func (r *FileRepo[T]) store(key string, value T, expire *time.Time) bool {
+	if err := r.append(journalEntry[T]{Op: opPut, Key: key, Schema: SchemaVersion[T](), Value: &value, Expire: expire}); err != nil {
+		log.Printf("Write repo journal failed %v", err)
+		return false
+	}
+	var (
+		old    T
+		exists bool
+	)
+	if r.watch.active() {
+		old, exists = r.Get(key)
+	}
+	r.data.Store(key, value)
+	r.setExpire(key, expire)
+	r.reserve.release(key)
+	r.index.put(key, value)
+	r.watch.emit(Event[T]{Type: EventPut, Key: key, Old: old, Exists: exists, New: value})
+	return true
+}
+
+func (r *FileRepo[T]) ExpiresAt(key string) (time.Time, bool) {
+	if _, ok := r.Get(key); !ok {
+		return time.Time{}, false
+	}
+	if expire := r.expire(key); expire != nil {
+		return *expire, true
+	}
+	return time.Time{}, false
+}
+
+func (r *FileRepo[T]) Expire(key string, ttl time.Duration) bool {
+	r.compact.RLock()
+	defer r.compact.RUnlock()
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
+
+	if _, ok := r.Get(key); !ok {
+		return false
+	}
+	var expire *time.Time
+	if ttl > 0 {
+		at := time.Now().Add(ttl)
+		expire = &at
+	}
+	if err := r.append(journalEntry[T]{Op: opExpire, Key: key, Expire: expire}); err != nil {
+		log.Printf("Write repo journal failed %v", err)
+		return false
+	}
+	r.setExpire(key, expire)
+	return true
+}
+
+func (r *FileRepo[T]) Update(key string, f func(old T, exists bool) (T, bool)) (T, bool) {
+	r.compact.RLock()
+	defer r.compact.RUnlock()
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
+
+	old, exists := r.Get(key)
+	var expire *time.Time
+	if exists {
+		expire = r.expire(key)
+	}
+	value, ok := f(old, exists)
+	if !ok || !r.store(key, value, expire) {
+		return old, false
+	}
+	return value, true
+}
+
+func (r *FileRepo[T]) Del(key string) bool {
+	r.compact.RLock()
+	defer r.compact.RUnlock()
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
+
+	r.reserve.release(key)
+	old, exists := r.Get(key)
+	if !exists {
+		return false
+	}
+	if err := r.append(journalEntry[T]{Op: opDel, Key: key}); err != nil {
+		log.Printf("Write repo journal failed %v", err)
+		return false
+	}
+	r.data.Delete(key)
+	r.expires.Delete(key)
+	r.index.del(key)
+	r.watch.emit(Event[T]{Type: EventDel, Key: key, Old: old, Exists: true})
+	return true
+}
+
+func (r *FileRepo[T]) Reserve(key string, ttl time.Duration) bool {
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
+
+	if _, ok := r.Get(key); ok {
+		return false
+	}
+	return r.reserve.take(key, ttl)
+}
+
+// Watch subscribes to changes of the repo. Expired keys are reported when the
+// next compaction drops them.
+func (r *FileRepo[T]) Watch(prefix string, f func(Event[T])) (cancel func()) {
+	return r.watch.watch(prefix, f)
+}
+
+func (r *FileRepo[T]) AddIndex(name string, f IndexFunc[T]) {
+	r.index.add(name, f, r.Range)
+}
+
+func (r *FileRepo[T]) Query(index, value, cursor string, limit int) (Page[T], error) {
+	return r.index.query(index, value, cursor, limit, r.Get)
+}
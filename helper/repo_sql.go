@@ -0,0 +1,438 @@
+package helper
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqlRangePage = 256
+
+// SQLRepo stores each key as a row of an embedded SQLite database, with the
+// value encoded as JSON. Nothing is cached in memory, so the repo can grow
+// beyond what FileRepo is able to hold and rewrite.
+type SQLRepo[T any] struct {
+	dir      string
+	name     string
+	filename string
+	db       *sql.DB
+	locks    keyLock
+	reserve  reservations
+	index    repoIndex[T]
+	cleanup  *time.Ticker
+	stop     chan struct{}
+}
+
+func NewSQLRepo[T any](dir string, name string, opts ...RepoOption) *SQLRepo[T] {
+	repo, err := OpenSQLRepo[T](dir, name, opts...)
+	if err != nil {
+		log.Panicf("Error create sql repo: %v", err)
+	}
+	return repo
+}
+
+// OpenSQLRepo is NewSQLRepo returning an error instead of panicking.
+func OpenSQLRepo[T any](dir string, name string, opts ...RepoOption) (*SQLRepo[T], error) {
+	var (
+		cfg      = newRepoConfig(opts)
+		fn       = name + "_" + strconv.FormatInt(cfg.botID, 10) + "_data.db"
+		filename = filepath.Join(dir, fn)
+	)
+
+	db, err := sql.Open("sqlite", "file:"+filename+"?_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open sql repo %s: %w", name, err)
+	}
+	// SQLite allows a single writer; one connection keeps concurrent writes
+	// from failing with SQLITE_BUSY instead of waiting their turn.
+	db.SetMaxOpenConns(1)
+
+	if _, err = db.Exec(`CREATE TABLE IF NOT EXISTS repo (key TEXT PRIMARY KEY, value TEXT NOT NULL, expire INTEGER, schema INTEGER NOT NULL DEFAULT 0)`); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("create table of sql repo %s: %w", name, err)
+	}
+	if err = sqlAddColumn(db, "expire", "INTEGER"); err == nil {
+		err = sqlAddColumn(db, "schema", "INTEGER NOT NULL DEFAULT 0")
+	}
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("migrate table of sql repo %s: %w", name, err)
+	}
+	log.Printf("Load SQLite(filename=%s) data success", filename)
+
+	repo := &SQLRepo[T]{
+		dir:      dir,
+		name:     name,
+		filename: fn,
+		db:       db,
+		stop:     make(chan struct{}),
+	}
+
+	if err = repo.upgrade(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("upgrade records of sql repo %s: %w", name, err)
+	}
+
+	repo.cleanup = time.NewTicker(time.Minute)
+	go repo.cleanupExpired()
+
+	return repo, nil
+}
+
+// sqlAddColumn upgrades tables created before column existed.
+func sqlAddColumn(db *sql.DB, column, decl string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('repo')`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	_ = rows.Close()
+	_, err = db.Exec(`ALTER TABLE repo ADD COLUMN ` + column + ` ` + decl)
+	return err
+}
+
+// upgrade rewrites every record stored with an older SchemaVersion of T.
+func (r *SQLRepo[T]) upgrade() error {
+	version := SchemaVersion[T]()
+	rows, err := r.db.Query(`SELECT key FROM repo WHERE schema < ?`, version)
+	if err != nil {
+		return err
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err = rows.Scan(&key); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		keys = append(keys, key)
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		var (
+			raw    string
+			schema int
+		)
+		if err = r.db.QueryRow(`SELECT value, schema FROM repo WHERE key = ?`, key).Scan(&raw, &schema); err != nil {
+			return err
+		}
+		value, err := decodeRecord[T](schema, json.RawMessage(raw))
+		if err != nil {
+			return errors.New("key " + key + ": " + err.Error())
+		}
+		if err = r.update(r.db, key, value); err != nil {
+			return err
+		}
+	}
+	if len(keys) > 0 {
+		log.Printf("Upgrade SQLite(filename=%s) %d records to schema %d success", r.filename, len(keys), version)
+	}
+	return nil
+}
+
+func (r *SQLRepo[T]) cleanupExpired() {
+	for {
+		select {
+		case <-r.cleanup.C:
+			if err := r.deleteExpired(); err != nil {
+				log.Printf("Cleanup sql repo failed %v", err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func (r *SQLRepo[T]) deleteExpired() error {
+	rows, err := r.db.Query(`DELETE FROM repo WHERE expire IS NOT NULL AND expire <= ? RETURNING key`, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		if err = rows.Scan(&key); err != nil {
+			return err
+		}
+		r.index.del(key)
+	}
+	return rows.Err()
+}
+
+// sqlExpire converts a ttl into the expire column value, NULL meaning never.
+func sqlExpire(ttl time.Duration) sql.NullInt64 {
+	if ttl <= 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: time.Now().Add(ttl).UnixMilli(), Valid: true}
+}
+
+func (r *SQLRepo[T]) Type() string {
+	return string(SQL)
+}
+
+func (r *SQLRepo[T]) Name() string {
+	return r.name
+}
+
+func (r *SQLRepo[T]) DataSize() int64 {
+	var size int64
+	for _, suffix := range []string{"", "-wal"} {
+		if fileInfo, err := os.Stat(filepath.Join(r.dir, r.filename+suffix)); err == nil {
+			size += fileInfo.Size()
+		}
+	}
+	return size
+}
+
+func (r *SQLRepo[T]) Size() int {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM repo WHERE expire IS NULL OR expire > ?`, time.Now().UnixMilli()).Scan(&count); err != nil {
+		log.Printf("Count sql repo failed %v", err)
+		return 0
+	}
+	return count
+}
+
+// Range walks the rows in key order one page at a time, so f may call back
+// into the repo without holding the connection.
+func (r *SQLRepo[T]) Range(f func(key string, value T) bool) {
+	after := ""
+	for {
+		keys, values, last, scanned, err := r.page(after)
+		if err != nil {
+			log.Printf("Range sql repo failed %v", err)
+			return
+		}
+		for i, key := range keys {
+			if !f(key, values[i]) {
+				return
+			}
+		}
+		// rows that failed to decode still count towards a full page
+		if scanned < sqlRangePage {
+			return
+		}
+		after = last
+	}
+}
+
+// page returns the decodable rows after the key after, the last key scanned
+// and the number of rows scanned.
+func (r *SQLRepo[T]) page(after string) (keys []string, values []T, last string, scanned int, err error) {
+	rows, err := r.db.Query(`SELECT key, value, schema FROM repo WHERE key > ? AND (expire IS NULL OR expire > ?) ORDER BY key LIMIT ?`, after, time.Now().UnixMilli(), sqlRangePage)
+	if err != nil {
+		return nil, nil, "", 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			key, raw string
+			schema   int
+		)
+		if err = rows.Scan(&key, &raw, &schema); err != nil {
+			return nil, nil, "", 0, err
+		}
+		last = key
+		scanned++
+		value, err := decodeRecord[T](schema, json.RawMessage(raw))
+		if err != nil {
+			log.Printf("Decode sql repo row(key=%s) failed %v", key, err)
+			continue
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	return keys, values, last, scanned, rows.Err()
+}
+
+func (r *SQLRepo[T]) Stop() {
+	close(r.stop)
+	r.cleanup.Stop()
+	if err := r.db.Close(); err != nil {
+		log.Printf("Close sql repo failed %v", err)
+	}
+}
+
+func (r *SQLRepo[T]) Get(key string) (T, bool) {
+	var zero T
+	value, ok, err := r.get(r.db, key)
+	if err != nil {
+		log.Printf("Get sql repo(key=%s) failed %v", key, err)
+		return zero, false
+	}
+	return value, ok
+}
+
+type sqlQueryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func (r *SQLRepo[T]) get(q sqlQueryer, key string) (T, bool, error) {
+	var (
+		zero   T
+		raw    string
+		schema int
+	)
+	err := q.QueryRow(`SELECT value, schema FROM repo WHERE key = ? AND (expire IS NULL OR expire > ?)`, key, time.Now().UnixMilli()).Scan(&raw, &schema)
+	if errors.Is(err, sql.ErrNoRows) {
+		return zero, false, nil
+	} else if err != nil {
+		return zero, false, err
+	}
+	value, err := decodeRecord[T](schema, json.RawMessage(raw))
+	if err != nil {
+		return zero, false, err
+	}
+	return value, true, nil
+}
+
+func (r *SQLRepo[T]) put(q sqlQueryer, key string, value T, expire sql.NullInt64) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(`INSERT INTO repo (key, value, expire, schema) VALUES (?, ?, ?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value, expire = excluded.expire, schema = excluded.schema`, key, string(raw), expire, SchemaVersion[T]())
+	return err
+}
+
+func (r *SQLRepo[T]) Put(key string, value T) bool {
+	return r.PutWithTTL(key, value, 0)
+}
+
+func (r *SQLRepo[T]) PutWithTTL(key string, value T, ttl time.Duration) bool {
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
+
+	if err := r.put(r.db, key, value, sqlExpire(ttl)); err != nil {
+		log.Printf("Put sql repo(key=%s) failed %v", key, err)
+		return false
+	}
+	r.reserve.release(key)
+	r.index.put(key, value)
+	return true
+}
+
+func (r *SQLRepo[T]) ExpiresAt(key string) (time.Time, bool) {
+	var expire sql.NullInt64
+	err := r.db.QueryRow(`SELECT expire FROM repo WHERE key = ? AND (expire IS NULL OR expire > ?)`, key, time.Now().UnixMilli()).Scan(&expire)
+	if err != nil || !expire.Valid {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(expire.Int64), true
+}
+
+func (r *SQLRepo[T]) Expire(key string, ttl time.Duration) bool {
+	res, err := r.db.Exec(`UPDATE repo SET expire = ? WHERE key = ? AND (expire IS NULL OR expire > ?)`, sqlExpire(ttl), key, time.Now().UnixMilli())
+	if err != nil {
+		log.Printf("Expire sql repo(key=%s) failed %v", key, err)
+		return false
+	}
+	n, err := res.RowsAffected()
+	return err == nil && n > 0
+}
+
+// Update runs f under the key lock of the repo rather than in a
+// transaction, which would hold the only connection while f runs, so f may
+// read the repo like with the other backends.
+func (r *SQLRepo[T]) Update(key string, f func(old T, exists bool) (T, bool)) (T, bool) {
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
+
+	old, exists, err := r.get(r.db, key)
+	if err != nil {
+		log.Printf("Update sql repo(key=%s) failed %v", key, err)
+		return old, false
+	}
+	value, ok := f(old, exists)
+	if !ok {
+		return old, false
+	}
+	if exists {
+		err = r.update(r.db, key, value)
+	} else {
+		err = r.put(r.db, key, value, sql.NullInt64{})
+	}
+	if err != nil {
+		log.Printf("Update sql repo(key=%s) failed %v", key, err)
+		return old, false
+	}
+	r.reserve.release(key)
+	r.index.put(key, value)
+	return value, true
+}
+
+// update rewrites the value of an existing row and keeps its expire.
+func (r *SQLRepo[T]) update(q sqlQueryer, key string, value T) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(`UPDATE repo SET value = ?, schema = ? WHERE key = ?`, string(raw), SchemaVersion[T](), key)
+	return err
+}
+
+func (r *SQLRepo[T]) Del(key string) bool {
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
+
+	r.reserve.release(key)
+	res, err := r.db.Exec(`DELETE FROM repo WHERE key = ?`, key)
+	if err != nil {
+		log.Printf("Del sql repo(key=%s) failed %v", key, err)
+		return false
+	}
+	r.index.del(key)
+	n, err := res.RowsAffected()
+	return err == nil && n > 0
+}
+
+func (r *SQLRepo[T]) Reserve(key string, ttl time.Duration) bool {
+	mu := r.locks.lock(key)
+	defer mu.Unlock()
+
+	_, ok, err := r.get(r.db, key)
+	if err != nil {
+		log.Printf("Reserve sql repo(key=%s) failed %v", key, err)
+		return false
+	}
+	return !ok && r.reserve.take(key, ttl)
+}
+
+func (r *SQLRepo[T]) AddIndex(name string, f IndexFunc[T]) {
+	r.index.add(name, f, r.Range)
+}
+
+func (r *SQLRepo[T]) Query(index, value, cursor string, limit int) (Page[T], error) {
+	return r.index.query(index, value, cursor, limit, r.Get)
+}
+
+func (r *SQLRepo[T]) ShardDataSizes() []int64 {
+	return []int64{r.DataSize()}
+}
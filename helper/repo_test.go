@@ -1,16 +1,59 @@
 package helper
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/go-playground/assert/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestMemRepo(t *testing.T) {
+	repo := NewMemRepo[int]("test")
+	defer repo.Stop()
+	testRepoUpdate(t, repo)
+}
+
+func TestMemRepoTTL(t *testing.T) {
+	sliding := NewMemRepo[int]("sliding", WithTTL(50*time.Millisecond))
+	defer sliding.Stop()
+	absolute := NewMemRepo[int]("absolute", WithTTL(50*time.Millisecond), WithTTLMode(AbsoluteTTL))
+	defer absolute.Stop()
 
+	for _, repo := range []*MemRepo[int]{sliding, absolute} {
+		repo.Put("a", 1)
+		repo.PutWithTTL("b", 2, time.Millisecond)
+		time.Sleep(30 * time.Millisecond)
+		_, ok := repo.Get("a")
+		assert.Equal(t, ok, true)
+		time.Sleep(30 * time.Millisecond)
+		// expired keys are not counted before the cleanup removes them
+		size := 0
+		if repo.mode == SlidingTTL {
+			size = 1
+		}
+		assert.Equal(t, repo.Size(), size)
+
+		// the slid key is scheduled again instead of removed
+		for _, key := range repo.expiry.due(time.Now()) {
+			repo.expire(key)
+		}
+		_, ok = repo.Get("a")
+		assert.Equal(t, ok, repo.mode == SlidingTTL)
+		assert.Equal(t, repo.Size(), repo.expiry.len())
+		_, ok = repo.data.Load("b")
+		assert.Equal(t, ok, false)
+	}
 }
 
 func TestFileRepo(t *testing.T) {
-	repo := NewFileRepo("", "test")
+	repo := NewFileRepo[any](t.TempDir(), "test")
 	defer repo.Stop()
 	repo.Put("test", "test")
 	repo.Put("1", 1)
@@ -20,3 +63,569 @@ func TestFileRepo(t *testing.T) {
 	assert.Equal(t, i, 1)
 	repo.Sync()
 }
+
+func TestFileRepoUpdate(t *testing.T) {
+	repo := NewFileRepo[int](t.TempDir(), "test")
+	defer repo.Stop()
+	testRepoUpdate(t, repo)
+}
+
+func testRepoUpdate(t *testing.T, repo Repo[int]) {
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repo.Update("counter", func(old int, exists bool) (int, bool) {
+				return old + 1, true
+			})
+		}()
+	}
+	wg.Wait()
+	v, ok := repo.Get("counter")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, v, 100)
+
+	v, ok = repo.Update("counter", func(old int, exists bool) (int, bool) {
+		return 0, false
+	})
+	assert.Equal(t, ok, false)
+	assert.Equal(t, v, 100)
+}
+
+func TestFileRepoJournalReplay(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewFileRepo[int](dir, "journal")
+	repo.Put("a", 1)
+	repo.Put("b", 2)
+	repo.Del("a")
+	repo.Put("c", 3)
+
+	// simulate a crash in the middle of the next journal write
+	_, _ = repo.shards[0].jfile.WriteString(`{"op":"put","key":"d","val`)
+	_ = repo.shards[0].jfile.Close()
+
+	reloaded := NewFileRepo[int](dir, "journal")
+	defer reloaded.Stop()
+	_, ok := reloaded.Get("a")
+	assert.Equal(t, ok, false)
+	v, ok := reloaded.Get("b")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, v, 2)
+	v, ok = reloaded.Get("c")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, v, 3)
+	assert.Equal(t, reloaded.Size(), 2)
+}
+
+func TestSQLRepo(t *testing.T) {
+	repo := NewSQLRepo[int](t.TempDir(), "test")
+	defer repo.Stop()
+	testRepoUpdate(t, repo)
+
+	for i := 0; i < 300; i++ {
+		repo.Put(RandomString(8), i)
+	}
+	count := 0
+	repo.Range(func(key string, value int) bool {
+		count++
+		return true
+	})
+	assert.Equal(t, count, repo.Size())
+	assert.Equal(t, repo.Del("counter"), true)
+	assert.Equal(t, repo.Del("counter"), false)
+}
+
+func TestSQLRepoRangeBadRow(t *testing.T) {
+	repo := NewSQLRepo[int](t.TempDir(), "test")
+	defer repo.Stop()
+	for i := 0; i < 2*sqlRangePage; i++ {
+		repo.Put(fmt.Sprintf("k%04d", i), i)
+	}
+	// a bad row on the first page must not end the walk early
+	_, err := repo.db.Exec(`UPDATE repo SET value = 'bad' WHERE key = 'k0010'`)
+	assert.Equal(t, err, nil)
+
+	count := 0
+	repo.Range(func(key string, value int) bool {
+		count++
+		return true
+	})
+	assert.Equal(t, count, 2*sqlRangePage-1)
+}
+
+func TestSQLRepoUpdateReads(t *testing.T) {
+	repo := NewSQLRepo[int](t.TempDir(), "test")
+	defer repo.Stop()
+	repo.Put("a", 1)
+
+	// f may read the repo while Update runs
+	value, ok := repo.Update("b", func(old int, exists bool) (int, bool) {
+		a, _ := repo.Get("a")
+		return a + 1, true
+	})
+	assert.Equal(t, ok, true)
+	assert.Equal(t, value, 2)
+}
+
+// TestRepoNoTTL checks that a ttl <= 0 means no ttl on every backend.
+func TestRepoNoTTL(t *testing.T) {
+	mem := NewMemRepo[int]("test", WithTTL(time.Millisecond))
+	defer mem.Stop()
+	file := NewFileRepo[int](t.TempDir(), "test")
+	defer file.Stop()
+	sql := NewSQLRepo[int](t.TempDir(), "test")
+	defer sql.Stop()
+
+	for _, repo := range []interface {
+		Repo[int]
+		ExpiryRepo
+	}{mem, file, sql} {
+		repo.PutWithTTL("kept", 1, 0)
+		repo.PutWithTTL("reset", 2, time.Hour)
+		assert.Equal(t, repo.Expire("reset", 0), true)
+		repo.PutWithTTL("short", 3, time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+
+		for _, key := range []string{"kept", "reset"} {
+			_, ok := repo.Get(key)
+			assert.Equal(t, ok, true)
+			_, ok = repo.ExpiresAt(key)
+			assert.Equal(t, ok, false)
+		}
+		_, ok := repo.Get("short")
+		assert.Equal(t, ok, false)
+	}
+}
+
+// TestRepoReserve checks that a reserved key blocks other reservations but
+// holds no value, on every backend.
+func TestRepoReserve(t *testing.T) {
+	mem := NewMemRepo[int]("test")
+	defer mem.Stop()
+	file := NewFileRepo[int](t.TempDir(), "test")
+	defer file.Stop()
+	sql := NewSQLRepo[int](t.TempDir(), "test")
+	defer sql.Stop()
+
+	for _, repo := range []interface {
+		Repo[int]
+		RepoInfo
+		Reserver
+	}{mem, file, sql} {
+		assert.Equal(t, repo.Reserve("a", time.Minute), true)
+		assert.Equal(t, repo.Reserve("a", time.Minute), false)
+		_, ok := repo.Get("a")
+		assert.Equal(t, ok, false)
+		assert.Equal(t, repo.Size(), 0)
+
+		// storing ends the reservation, the stored key stays taken
+		repo.Put("a", 1)
+		assert.Equal(t, repo.Reserve("a", time.Minute), false)
+		repo.Del("a")
+		assert.Equal(t, repo.Reserve("a", time.Minute), true)
+		repo.Del("a")
+
+		assert.Equal(t, repo.Reserve("b", time.Millisecond), true)
+		time.Sleep(5 * time.Millisecond)
+		assert.Equal(t, repo.Reserve("b", time.Minute), true)
+	}
+}
+
+func TestFileRepoTTL(t *testing.T) {
+	dir := t.TempDir()
+	// a snapshot written before expirations existed
+	err := os.WriteFile(filepath.Join(dir, "ttl_0_data.json"), []byte(`{"legacy":7}`), 0644)
+	assert.Equal(t, err, nil)
+
+	repo := NewFileRepo[int](dir, "ttl")
+	v, ok := repo.Get("legacy")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, v, 7)
+
+	repo.PutWithTTL("short", 1, time.Millisecond)
+	repo.PutWithTTL("long", 2, time.Hour)
+	repo.Put("forever", 3)
+	assert.Equal(t, repo.Expire("forever", time.Millisecond), true)
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok = repo.Get("short")
+	assert.Equal(t, ok, false)
+	_, ok = repo.Get("forever")
+	assert.Equal(t, ok, false)
+	assert.Equal(t, repo.Size(), 2)
+	repo.Stop()
+
+	reloaded := NewFileRepo[int](dir, "ttl")
+	defer reloaded.Stop()
+	assert.Equal(t, reloaded.Size(), 2)
+	assert.Equal(t, reloaded.expire("long") != nil, true)
+}
+
+func TestRepoQuery(t *testing.T) {
+	mem := NewMemRepo[int]("test")
+	defer mem.Stop()
+	file := NewFileRepo[int](t.TempDir(), "test")
+	defer file.Stop()
+
+	for _, repo := range []interface {
+		Repo[int]
+		Indexer[int]
+	}{mem, file} {
+		repo.Put("a", 1)
+		repo.Put("b", 2)
+		repo.AddIndex("odd", func(v int) string {
+			if v%2 == 1 {
+				return "odd"
+			}
+			return "even"
+		})
+		repo.Put("c", 3)
+		repo.Put("d", 5)
+		repo.Put("e", 7)
+		repo.Del("d")
+		repo.Put("b", 9)
+
+		page, err := repo.Query("odd", "odd", "", 2)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, page.Keys, []string{"a", "b"})
+		page, err = repo.Query("odd", "odd", page.Next, 2)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, page.Keys, []string{"c", "e"})
+		assert.Equal(t, page.Values, []int{3, 7})
+		page, _ = repo.Query("odd", "even", "", 0)
+		assert.Equal(t, len(page.Keys), 0)
+		_, err = repo.Query("missing", "odd", "", 0)
+		assert.Equal(t, err, ErrIndexNotFound)
+	}
+}
+
+type schemaTestRecord struct {
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+}
+
+func TestFileRepoMigration(t *testing.T) {
+	dir := t.TempDir()
+	// version 0 stored the score as "points"
+	err := os.WriteFile(filepath.Join(dir, "schema_0_data.json"), []byte(`{"a":{"name":"a","points":3}}`), 0644)
+	assert.Equal(t, err, nil)
+	err = os.WriteFile(filepath.Join(dir, "schema_0_data.journal"), []byte(`{"op":"put","key":"b","value":{"name":"b","points":4}}`+"\n"), 0644)
+	assert.Equal(t, err, nil)
+
+	RegisterSchema[schemaTestRecord](func(record json.RawMessage) (json.RawMessage, error) {
+		var m map[string]any
+		if err := json.Unmarshal(record, &m); err != nil {
+			return nil, err
+		}
+		m["score"] = m["points"]
+		delete(m, "points")
+		return json.Marshal(m)
+	})
+	defer schemas.Delete(schemaType[schemaTestRecord]())
+
+	repo := NewFileRepo[schemaTestRecord](dir, "schema")
+	a, _ := repo.Get("a")
+	b, _ := repo.Get("b")
+	assert.Equal(t, a.Score, 3)
+	assert.Equal(t, b.Score, 4)
+	repo.Stop()
+
+	// the upgraded snapshot must not be migrated twice
+	reloaded := NewFileRepo[schemaTestRecord](dir, "schema")
+	defer reloaded.Stop()
+	a, _ = reloaded.Get("a")
+	assert.Equal(t, a.Score, 3)
+
+	_, err = decodeRecord[schemaTestRecord](2, json.RawMessage(`{}`))
+	assert.NotEqual(t, err, nil)
+}
+
+func TestFileRepoCodec(t *testing.T) {
+	dir := t.TempDir()
+	configs := [][]RepoOption{
+		{WithCodec(GobCodec)},
+		{WithCodec(MsgpackCodec), WithCompression(ZstdCompression)},
+		{WithCodec(JSONCodec), WithCompression(GzipCompression)},
+		nil,
+	}
+	for i, opts := range configs {
+		// every repo reopens the files written by the previous configuration
+		repo := NewFileRepo[schemaTestRecord](dir, "codec", opts...)
+		for j := 0; j <= i; j++ {
+			value, ok := repo.Get(strconv.Itoa(j))
+			assert.Equal(t, ok, j < i)
+			if ok {
+				assert.Equal(t, value.Score, j)
+			}
+		}
+		repo.Put(strconv.Itoa(i), schemaTestRecord{Name: "r", Score: i})
+		repo.PutWithTTL("expiring", schemaTestRecord{}, time.Hour)
+		// leave the last put in the journal
+		_ = repo.shards[0].jfile.Close()
+		close(repo.stop)
+	}
+}
+
+func TestFileRepoCodecMigration(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewFileRepo[map[string]any](dir, "schema", WithCodec(MsgpackCodec))
+	repo.Put("a", map[string]any{"name": "a", "points": 3})
+	_ = repo.Sync()
+	repo.Put("b", map[string]any{"name": "b", "points": 4})
+	_ = repo.shards[0].jfile.Close()
+	close(repo.stop)
+
+	RegisterSchema[schemaTestRecord](func(record json.RawMessage) (json.RawMessage, error) {
+		var m map[string]any
+		if err := json.Unmarshal(record, &m); err != nil {
+			return nil, err
+		}
+		m["score"] = m["points"]
+		delete(m, "points")
+		return json.Marshal(m)
+	})
+	defer schemas.Delete(schemaType[schemaTestRecord]())
+
+	migrated := NewFileRepo[schemaTestRecord](dir, "schema", WithCodec(MsgpackCodec))
+	defer migrated.Stop()
+	a, _ := migrated.Get("a")
+	b, _ := migrated.Get("b")
+	assert.Equal(t, a.Score, 3)
+	assert.Equal(t, b.Score, 4)
+}
+
+func TestFileRepoShards(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewFileRepo[int](dir, "shard", WithShards(4))
+	for i := 0; i < 100; i++ {
+		repo.Put(strconv.Itoa(i), i)
+	}
+	assert.Equal(t, repo.Sync(), nil)
+	assert.Equal(t, len(repo.ShardDataSizes()), 4)
+
+	// only the shard of the changed key is rewritten
+	stat := func(i int) os.FileInfo {
+		info, err := os.Stat(filepath.Join(dir, repo.shards[i].filename))
+		assert.Equal(t, err, nil)
+		return info
+	}
+	var before []os.FileInfo
+	for i := range repo.shards {
+		before = append(before, stat(i))
+	}
+	repo.Put("7", 70)
+	assert.Equal(t, repo.Sync(), nil)
+	for i := range repo.shards {
+		assert.Equal(t, os.SameFile(before[i], stat(i)), i != repo.shardIndex("7"))
+	}
+	repo.Stop()
+
+	// changing the shard count moves every key and removes the old files
+	for _, n := range []int{2, 1} {
+		repo = NewFileRepo[int](dir, "shard", WithShards(n))
+		assert.Equal(t, repo.Size(), 100)
+		value, _ := repo.Get("7")
+		assert.Equal(t, value, 70)
+		repo.Stop()
+		matches, _ := filepath.Glob(filepath.Join(dir, "shard_*_data.json"))
+		assert.Equal(t, len(matches), n)
+	}
+}
+
+func TestRepoStopClosesWatch(t *testing.T) {
+	repo := NewMemRepo[int]("test")
+	cancel := repo.Watch("", func(Event[int]) {})
+	repo.Stop()
+	assert.Equal(t, repo.watch.active(), false)
+	assert.Equal(t, len(repo.watch.subs), 0)
+	// cancelling after the stop does nothing
+	cancel()
+}
+
+func TestRepoWatch(t *testing.T) {
+	mem := NewMemRepo[int]("test")
+	defer mem.Stop()
+	file := NewFileRepo[int](t.TempDir(), "test")
+	defer file.Stop()
+
+	for _, repo := range []interface {
+		Repo[int]
+		Watcher[int]
+	}{mem, file} {
+		events := make(chan Event[int], 16)
+		cancel := repo.Watch("a", func(e Event[int]) {
+			events <- e
+		})
+		repo.Put("a1", 1)
+		repo.Put("b1", 1)
+		repo.Update("a1", func(old int, exists bool) (int, bool) {
+			return old + 1, true
+		})
+		repo.Del("a1")
+		repo.PutWithTTL("a2", 3, time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+		if f, ok := repo.(*FileRepo[int]); ok {
+			_ = f.Sync()
+		} else {
+			repo.Get("a2")
+		}
+
+		next := func() Event[int] {
+			select {
+			case e := <-events:
+				return e
+			case <-time.After(time.Second):
+				t.Fatal("event not delivered")
+				return Event[int]{}
+			}
+		}
+		assert.Equal(t, next(), Event[int]{Type: EventPut, Key: "a1", New: 1})
+		assert.Equal(t, next(), Event[int]{Type: EventPut, Key: "a1", Old: 1, Exists: true, New: 2})
+		assert.Equal(t, next(), Event[int]{Type: EventDel, Key: "a1", Old: 2, Exists: true})
+		assert.Equal(t, next(), Event[int]{Type: EventPut, Key: "a2", New: 3})
+		assert.Equal(t, next(), Event[int]{Type: EventExpire, Key: "a2", Old: 3, Exists: true})
+		cancel()
+	}
+}
+
+// scanExpired is the cleanup MemRepo used before expirations were kept in a
+// heap: every tick visited every key.
+func scanExpired[T any](r *MemRepo[T]) {
+	r.data.Range(func(key, value any) bool {
+		if item, ok := value.(Item[T]); ok && time.Now().After(item.expiration) {
+			r.expire(key.(string))
+		}
+		return true
+	})
+}
+
+// BenchmarkMemRepoCleanup measures one cleanup tick of a repo holding 100k
+// keys of which 100 are due.
+func BenchmarkMemRepoCleanup(b *testing.B) {
+	for _, bench := range []struct {
+		name    string
+		cleanup func(r *MemRepo[int])
+	}{
+		{"scan", scanExpired[int]},
+		{"heap", func(r *MemRepo[int]) {
+			for _, key := range r.expiry.due(time.Now()) {
+				r.expire(key)
+			}
+		}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			repo := NewMemRepo[int]("bench")
+			defer repo.Stop()
+			for i := 0; i < 100000; i++ {
+				repo.Put(strconv.Itoa(i), i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				for j := 0; j < 100; j++ {
+					repo.set("due"+strconv.Itoa(j), j, time.Nanosecond)
+				}
+				b.StartTimer()
+				bench.cleanup(repo)
+			}
+		})
+	}
+}
+
+func BenchmarkMemRepoGet(b *testing.B) {
+	for _, bench := range []struct {
+		name string
+		mode TTLMode
+	}{
+		{"sliding", SlidingTTL},
+		{"absolute", AbsoluteTTL},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			repo := NewMemRepo[int]("bench", WithTTLMode(bench.mode))
+			defer repo.Stop()
+			for i := 0; i < 1000; i++ {
+				repo.Put(strconv.Itoa(i), i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				repo.Get(strconv.Itoa(i % 1000))
+			}
+		})
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	repo := NewMemRepo[int]("test")
+	defer repo.Stop()
+	namespaces := NewNamespaces[int](repo)
+	users := namespaces.Sub(NamespaceUser)
+	chats := namespaces.Sub(NamespaceChat)
+
+	users.Put(ID(1), 1)
+	users.Put(ID(2), 2)
+	chats.Put(ID(1), 3)
+	repo.Put("other", 4)
+
+	value, _ := chats.Get(ID(1))
+	assert.Equal(t, value, 3)
+	keys := 0
+	users.Range(func(key string, value int) bool {
+		keys++
+		assert.Equal(t, key, ID(int64(value)))
+		return true
+	})
+	assert.Equal(t, keys, 2)
+	assert.Equal(t, namespaces.NamespaceSizes(), map[Namespace]int{NamespaceUser: 2, NamespaceChat: 1})
+
+	defer func() {
+		assert.NotEqual(t, recover(), nil)
+	}()
+	namespaces.Sub(NamespaceUser)
+}
+
+func TestLockDir(t *testing.T) {
+	dir := t.TempDir()
+	unlock, err := LockDir(dir)
+	assert.Equal(t, err, nil)
+	_, err = LockDir(dir)
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, unlock(), nil)
+
+	unlock, err = LockDir(dir)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, unlock(), nil)
+}
+
+func TestFileRepoEncryption(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, _ := NewKeyring(bytes.Repeat([]byte{1}, 32))
+	newKey, _ := NewKeyring(bytes.Repeat([]byte{2}, 32))
+	rotation, _ := NewKeyring(bytes.Repeat([]byte{2}, 32), bytes.Repeat([]byte{1}, 32))
+
+	repo := NewFileRepo[string](dir, "secret", WithKeyring(oldKey))
+	repo.Put("a", "plain text")
+	assert.Equal(t, repo.Sync(), nil)
+	repo.Put("b", "journal text")
+	_ = repo.shards[0].jfile.Close()
+	close(repo.stop)
+	for _, fn := range []string{repo.shards[0].filename, repo.shards[0].journal} {
+		raw, _ := os.ReadFile(filepath.Join(dir, fn))
+		assert.Equal(t, bytes.Contains(raw, []byte("text")), false)
+	}
+
+	_, err := OpenFileRepo[string](dir, "secret")
+	assert.Equal(t, errors.Is(err, ErrKeyMissing), true)
+	_, err = OpenFileRepo[string](dir, "secret", WithKeyring(newKey))
+	assert.Equal(t, errors.Is(err, ErrKeyWrong), true)
+
+	// the startup compaction re-encrypts with the new key
+	rotated, err := OpenFileRepo[string](dir, "secret", WithKeyring(rotation))
+	assert.Equal(t, err, nil)
+	rotated.Stop()
+	reopened, err := OpenFileRepo[string](dir, "secret", WithKeyring(newKey))
+	assert.Equal(t, err, nil)
+	defer reopened.Stop()
+	value, _ := reopened.Get("b")
+	assert.Equal(t, value, "journal text")
+}
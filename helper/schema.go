@@ -0,0 +1,59 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Migration upgrades one JSON encoded record by a single schema version.
+type Migration func(record json.RawMessage) (json.RawMessage, error)
+
+type schema struct {
+	migrations []Migration
+}
+
+var schemas sync.Map
+
+func schemaType[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// RegisterSchema declares the migrations of the persisted type T, where
+// migrations[i] upgrades a record from version i to i+1. Version 0 is the
+// layout written before T was versioned, so the current version of T is
+// len(migrations). Register schemas before creating repos of T, and append
+// a migration whenever the JSON layout of T changes.
+func RegisterSchema[T any](migrations ...Migration) {
+	schemas.Store(schemaType[T](), &schema{migrations: migrations})
+}
+
+// SchemaVersion returns the version repos stamp on records of T they write.
+func SchemaVersion[T any]() int {
+	if s, ok := schemas.Load(schemaType[T]()); ok {
+		return len(s.(*schema).migrations)
+	}
+	return 0
+}
+
+// decodeRecord upgrades a record written with schema version and decodes it.
+func decodeRecord[T any](version int, raw json.RawMessage) (T, error) {
+	var value T
+	var migrations []Migration
+	if s, ok := schemas.Load(schemaType[T]()); ok {
+		migrations = s.(*schema).migrations
+	}
+	if version > len(migrations) {
+		return value, fmt.Errorf("record schema version %d of %v is newer than supported version %d", version, schemaType[T](), len(migrations))
+	}
+	for i := version; i < len(migrations); i++ {
+		upgraded, err := migrations[i](raw)
+		if err != nil {
+			return value, fmt.Errorf("migrate %v from version %d failed: %w", schemaType[T](), i, err)
+		}
+		raw = upgraded
+	}
+	err := json.Unmarshal(raw, &value)
+	return value, err
+}
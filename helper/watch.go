@@ -0,0 +1,144 @@
+package helper
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type EventType string
+
+const (
+	EventPut    EventType = "put"
+	EventDel    EventType = "del"
+	EventExpire EventType = "expire"
+)
+
+// Event describes one change of a repo key. Old is set when Exists is true;
+// New is set for EventPut only.
+type Event[T any] struct {
+	Type   EventType
+	Key    string
+	Old    T
+	Exists bool
+	New    T
+}
+
+// Watcher is implemented by repos that publish their changes.
+type Watcher[T any] interface {
+	// Watch calls f for every change of a key starting with prefix until
+	// cancel is called. Events are delivered in order on a goroutine of the
+	// subscription, so a slow f never blocks the writer.
+	Watch(prefix string, f func(Event[T])) (cancel func())
+}
+
+type subscription[T any] struct {
+	prefix string
+	f      func(Event[T])
+	mu     sync.Mutex
+	queue  []Event[T]
+	wake   chan struct{}
+	done   chan struct{}
+}
+
+func (s *subscription[T]) push(e Event[T]) {
+	s.mu.Lock()
+	s.queue = append(s.queue, e)
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscription[T]) loop() {
+	for {
+		select {
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+		s.mu.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+		for _, e := range queue {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			s.f(e)
+		}
+	}
+}
+
+// watchers fans repo changes out to subscriptions. Repos emit while holding
+// the lock of the changed key, so events of one key arrive in write order.
+type watchers[T any] struct {
+	mu    sync.RWMutex
+	subs  map[*subscription[T]]struct{}
+	count atomic.Int32
+}
+
+func (w *watchers[T]) watch(prefix string, f func(Event[T])) func() {
+	s := &subscription[T]{
+		prefix: prefix,
+		f:      f,
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	w.mu.Lock()
+	if w.subs == nil {
+		w.subs = make(map[*subscription[T]]struct{})
+	}
+	w.subs[s] = struct{}{}
+	w.count.Add(1)
+	w.mu.Unlock()
+
+	go s.loop()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			if _, ok := w.subs[s]; ok {
+				delete(w.subs, s)
+				w.count.Add(-1)
+				close(s.done)
+			}
+		})
+	}
+}
+
+// close ends every subscription, so that no callback runs once the repo is
+// stopped. Cancelling a closed subscription does nothing.
+func (w *watchers[T]) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for s := range w.subs {
+		delete(w.subs, s)
+		w.count.Add(-1)
+		close(s.done)
+	}
+}
+
+// active reports whether anyone listens, so writers can skip loading the
+// old value when nobody does.
+func (w *watchers[T]) active() bool {
+	return w.count.Load() > 0
+}
+
+func (w *watchers[T]) emit(e Event[T]) {
+	if !w.active() {
+		return
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for s := range w.subs {
+		if strings.HasPrefix(e.Key, s.prefix) {
+			s.push(e)
+		}
+	}
+}
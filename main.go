@@ -1,11 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"log"
 	"ocha_server_bot/command"
 	"ocha_server_bot/command/mine"
 	"ocha_server_bot/helper"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"gopkg.in/telebot.v4"
@@ -13,50 +22,84 @@ import (
 )
 
 func main() {
-	token := os.Getenv("BOT_TOKEN")
-	log.Printf("Bot starting with Token(token=%s)", token)
-	pref := telebot.Settings{
-		Token:  token,
-		Poller: &telebot.LongPoller{Timeout: 10 * time.Second},
-		OnError: func(err error, context telebot.Context) {
-			log.Printf("Bot error(Default OnError): %v (in context: %v)", err, context.Text())
-		},
+	if err := run(); err != nil {
+		log.Fatal(err)
 	}
+}
 
-	home, err := os.UserHomeDir()
+// run starts the bots, or the offline command given as arguments, and
+// returns once they stopped. Errors are returned rather than fatal so the
+// deferred unlock and repo stops run before the process exits.
+func run() error {
+	cfg, err := loadConfig()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	bot, err := telebot.NewBot(pref)
+	unlock, err := helper.LockDir(cfg.Dir)
 	if err != nil {
-		log.Panicf("Error create bot: %v", err)
+		return err
 	}
+	defer unlock()
 
-	helper.BotName = bot.Me.Username
-	helper.BotID = bot.Me.ID
+	if len(os.Args) > 1 {
+		return offline(cfg, os.Args[1:])
+	}
 
-	repoTask := helper.NewFileRepo[command.Task](home, "task")
-	repoMine := helper.NewFileRepo[mine.Serialized](home, "mine")
-	repoLanguage := helper.NewFileRepo[string](home, "language")
-	repoRank := helper.NewFileRepo[mine.TelegramMineGameScore](home, "mine_rank")
+	var bots []*botInstance
+	defer func() {
+		log.Println("Bots stopping")
+		for _, b := range bots {
+			b.stop()
+		}
+	}()
+	for _, bc := range cfg.Bots {
+		b, err := startBot(bc, cfg.Dir, cfg.keyring)
+		if err != nil {
+			return err
+		}
+		bots = append(bots, b)
+	}
 
-	langRepo := helper.NewLanguageRepo(repoLanguage)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	return nil
+}
 
-	rank := helper.NewQueueRank[mine.TelegramMineGameScore](repoRank, 100, func(a mine.TelegramMineGameScore) float64 {
-		return a.Score
-	})
+// botInstance is one running bot with its own repos and schedulers. The
+// Telegram connection outlives them: restore replaces them while the bot
+// is stopped.
+type botInstance struct {
+	mu      sync.Mutex
+	bot     *telebot.Bot
+	owners  helper.Owners
+	dir     string
+	keyring *helper.Keyring
+	lang    atomic.Pointer[helper.LanguageRepo]
+	task    *command.TaskCommandExec
+	repos   *repos
+}
 
-	menu := command.NewMenuCommandExec(langRepo)
-	mi := command.NewMineCommandExec(repoMine, rank, langRepo, menu)
-	help := command.NewHelpCommandExec(langRepo)
-	lang := command.NewLanguageCommandExec(langRepo, menu)
-	task := command.NewTaskCommandExec(bot, repoTask, langRepo)
-	stat := command.NewStatusCommandExec([]helper.RepoInfo{repoLanguage, repoRank, repoMine, repoTask}, langRepo)
+func startBot(bc botConfig, dir string, keyring *helper.Keyring) (*botInstance, error) {
+	log.Printf("Bot starting with Token(token=%s)", bc.Token)
+	pref := telebot.Settings{
+		Token:  bc.Token,
+		Poller: &telebot.LongPoller{Timeout: 10 * time.Second},
+		OnError: func(err error, context telebot.Context) {
+			log.Printf("Bot error(Default OnError): %v (in context: %v)", err, context.Text())
+		},
+	}
+
+	bot, err := telebot.NewBot(pref)
+	if err != nil {
+		return nil, errors.New("create bot: " + err.Error())
+	}
+	b := &botInstance{bot: bot, owners: bc.Owners, dir: dir, keyring: keyring}
 
 	bot.Use(middleware.Recover(func(err error, c telebot.Context) {
 		log.Printf("Bot error: %v (in context: %v)", err, c.Text())
-		if err = c.Send(helper.Messages[langRepo.Context(c)]["error"].Execute(map[string]string{
+		if err = c.Send(helper.Messages[b.lang.Load().Context(c)]["error"].Execute(map[string]string{
 			"Username": c.Sender().Username,
 			"Message":  err.Error(),
 		})); err != nil {
@@ -64,6 +107,40 @@ func main() {
 		}
 	}))
 
+	if err = b.start(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// start opens the repos, builds the commands on them and starts handling
+// updates; the caller holds b.mu or is the only user of b.
+func (b *botInstance) start() error {
+	bot := b.bot
+	r, err := openRepos(b.dir, bot.Me.ID, b.keyring)
+	if err != nil {
+		return err
+	}
+
+	langRepo := helper.NewLanguageRepo(r.language)
+
+	rank := helper.NewKeyedRank[mine.TelegramMineGameScore](r.rank, 100, func(a mine.TelegramMineGameScore) float64 {
+		return a.Score
+	}, mine.ScoreBoard, helper.WithOwner(mine.ScoreOwner), helper.WithBans[mine.TelegramMineGameScore](r.bans))
+	// ratings keep every player, the lowest rated is only dropped past the capacity
+	ratings := helper.NewQueueRank(r.rating, 100000, mine.RatingScore,
+		helper.WithOwner(mine.RatingOwner), helper.WithLatest[mine.PlayerRating](), helper.WithBans[mine.PlayerRating](r.bans))
+
+	menu := command.NewMenuCommandExec(langRepo)
+	mi := command.NewMineCommandExec(r.mine, rank, r.best, r.subs, ratings, r.audit, b.owners, langRepo, menu)
+	help := command.NewHelpCommandExec(langRepo)
+	lang := command.NewLanguageCommandExec(langRepo, menu)
+	task := command.NewTaskCommandExec(bot, r.task, langRepo)
+	fame := command.NewFameCommandExec(bot, rank, r.fame, r.winners, langRepo)
+	stat := command.NewStatusCommandExec([]helper.RepoInfo{r.language, r.rank, r.best, r.subs, r.rating, r.bans, r.audit, r.fame, r.winners, r.mine, r.task}, []helper.NamespaceInfo{langRepo.Namespaces(), rank.Namespaces()}, langRepo)
+	backup := command.NewBackupCommandExec(r.backup(bot.Me.ID), b.restore, b.owners, langRepo)
+	b.lang.Store(langRepo)
+
 	bot.Handle("/mine", mi.Mine)
 	bot.Handle("\fmine", mi.Mine)
 	bot.Handle("\fflag", mi.Flag)
@@ -73,6 +150,14 @@ func main() {
 	bot.Handle("\fchange", mi.Change)
 
 	bot.Handle("/mine_rank", mi.MineRank)
+	bot.Handle("\fmine_rank", mi.MineRank)
+	bot.Handle("/mine_rating", mi.MineRating)
+	bot.Handle("\fmine_mod", mi.ModerateRank)
+	bot.Handle("/mine_ban", mi.MineBan)
+	bot.Handle("/mine_unban", mi.MineUnban)
+	bot.Handle("/mine_audit", mi.MineAudit)
+	bot.Handle("/mine_fame", fame.Fame)
+	bot.Handle("/mine_winners", fame.Winners)
 	bot.Handle("\fmine_r", mi.MineR)
 
 	bot.Handle("/help", help.Help)
@@ -92,6 +177,274 @@ func main() {
 	bot.Handle("/stat", stat.Status)
 	bot.Handle("/stat_mine", stat.StatusMine)
 
-	log.Println("Bot started")
-	bot.Start()
+	bot.Handle("/backup", backup.Backup)
+	bot.Handle("/restore", backup.Restore)
+	bot.Handle(telebot.OnDocument, func(c telebot.Context) error {
+		if strings.HasPrefix(c.Message().Caption, "/restore") {
+			return backup.Restore(c)
+		}
+		return nil
+	})
+
+	if err = fame.Start(task); err != nil {
+		r.stop()
+		return err
+	}
+	if err = task.Start(); err != nil {
+		log.Printf("Recover tasks of Bot(name=%s) failed: %v", bot.Me.Username, err)
+	}
+	b.task, b.repos = task, r
+	go bot.Start()
+	log.Printf("Bot(name=%s) started", bot.Me.Username)
+	return nil
+}
+
+// halt stops handling updates, the schedulers and the repos; the caller
+// holds b.mu.
+func (b *botInstance) halt() {
+	b.bot.Stop()
+	b.task.Stop()
+	b.repos.stop()
+	b.task, b.repos = nil, nil
+}
+
+func (b *botInstance) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.repos != nil {
+		b.halt()
+	}
+}
+
+// restore replaces the data of the bot with archive, like the offline
+// restore command: the bot is stopped, the archive restored into its repos,
+// and the bot started again, so ranks, tasks and caches are rebuilt from
+// the restored data. It returns the number of restored records.
+func (b *botInstance) restore(archive []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.repos == nil {
+		return 0, errors.New("bot is stopped")
+	}
+	log.Printf("Bot(name=%s) stopping to restore a backup", b.bot.Me.Username)
+	b.halt()
+	records, err := restoreRepos(b.dir, b.bot.Me.ID, b.keyring, bytes.NewReader(archive))
+	if err != nil {
+		log.Printf("Restore backup of Bot(name=%s) failed: %v", b.bot.Me.Username, err)
+	}
+	if e := b.start(); e != nil {
+		return records, errors.Join(err, e)
+	}
+	return records, err
+}
+
+// restoreRepos replaces the content of the repos of bot with archive. No
+// one else may use the repos meanwhile.
+func restoreRepos(dir string, botID int64, keyring *helper.Keyring, archive io.Reader) (int, error) {
+	r, err := openRepos(dir, botID, keyring)
+	if err != nil {
+		return 0, err
+	}
+	defer r.stop()
+	return r.backup(botID).Restore(archive)
+}
+
+type repos struct {
+	task     infoRepo[command.Task]
+	mine     infoRepo[mine.Serialized]
+	language infoRepo[string]
+	rank     infoRepo[mine.TelegramMineGameScore]
+	best     infoRepo[mine.TelegramMineGameScore]
+	subs     infoRepo[mine.Submission]
+	rating   infoRepo[mine.PlayerRating]
+	bans     infoRepo[helper.RankBan]
+	audit    infoRepo[mine.Audit]
+	fame     infoRepo[mine.Fame]
+	winners  infoRepo[mine.WinnersChat]
+	// keyring encrypts the repo files and backups, if set.
+	keyring *helper.Keyring
+}
+
+func openRepos(dir string, botID int64, keyring *helper.Keyring) (*repos, error) {
+	var (
+		r   = &repos{keyring: keyring}
+		err error
+	)
+	r.task, err = newRepo[command.Task](dir, "task", botID, keyring)
+	if err == nil {
+		r.mine, err = newRepo[mine.Serialized](dir, "mine", botID, keyring)
+	}
+	if err == nil {
+		r.language, err = newRepo[string](dir, "language", botID, keyring)
+	}
+	if err == nil {
+		r.rank, err = newRepo[mine.TelegramMineGameScore](dir, "mine_rank", botID, keyring)
+	}
+	if err == nil {
+		r.best, err = newRepo[mine.TelegramMineGameScore](dir, "mine_best", botID, keyring)
+	}
+	if err == nil {
+		r.subs, err = newRepo[mine.Submission](dir, "mine_submission", botID, keyring)
+	}
+	if err == nil {
+		r.rating, err = newRepo[mine.PlayerRating](dir, "mine_rating", botID, keyring)
+	}
+	if err == nil {
+		r.bans, err = newRepo[helper.RankBan](dir, "mine_ban", botID, keyring)
+	}
+	if err == nil {
+		r.audit, err = newRepo[mine.Audit](dir, "mine_audit", botID, keyring)
+	}
+	if err == nil {
+		r.fame, err = newRepo[mine.Fame](dir, "mine_fame", botID, keyring)
+	}
+	if err == nil {
+		r.winners, err = newRepo[mine.WinnersChat](dir, "mine_winners", botID, keyring)
+	}
+	if err != nil {
+		r.stop()
+		if errors.Is(err, helper.ErrKeyMissing) || errors.Is(err, helper.ErrKeyWrong) {
+			err = errors.New(err.Error() + ", configure the keys with REPO_KEYS or \"keys\" in BOT_CONFIG")
+		}
+		return nil, err
+	}
+	command.IndexTask(r.task)
+	mine.Index(r.mine)
+	mine.IndexFame(r.fame)
+	mine.IndexAudit(r.audit)
+	return r, nil
+}
+
+// backup registers every repo for /backup and /restore
+func (r *repos) backup(botID int64) *helper.Backup {
+	b := helper.NewBackup(botID, r.keyring)
+	helper.AddBackup(b, r.task.Name(), r.task)
+	helper.AddBackup(b, r.mine.Name(), r.mine)
+	helper.AddBackup(b, r.language.Name(), r.language)
+	helper.AddBackup(b, r.rank.Name(), r.rank)
+	helper.AddBackup(b, r.best.Name(), r.best)
+	helper.AddBackup(b, r.subs.Name(), r.subs)
+	helper.AddBackup(b, r.rating.Name(), r.rating)
+	helper.AddBackup(b, r.bans.Name(), r.bans)
+	helper.AddBackup(b, r.audit.Name(), r.audit)
+	helper.AddBackup(b, r.fame.Name(), r.fame)
+	helper.AddBackup(b, r.winners.Name(), r.winners)
+	return b
+}
+
+// stop stops the opened repos.
+func (r *repos) stop() {
+	if r.task != nil {
+		r.task.Stop()
+	}
+	if r.mine != nil {
+		r.mine.Stop()
+	}
+	if r.language != nil {
+		r.language.Stop()
+	}
+	if r.rank != nil {
+		r.rank.Stop()
+	}
+	if r.best != nil {
+		r.best.Stop()
+	}
+	if r.subs != nil {
+		r.subs.Stop()
+	}
+	if r.rating != nil {
+		r.rating.Stop()
+	}
+	if r.bans != nil {
+		r.bans.Stop()
+	}
+	if r.audit != nil {
+		r.audit.Stop()
+	}
+	if r.fame != nil {
+		r.fame.Stop()
+	}
+	if r.winners != nil {
+		r.winners.Stop()
+	}
+}
+
+type infoRepo[T any] interface {
+	helper.Repo[T]
+	helper.RepoInfo
+	helper.Indexer[T]
+}
+
+// repoEnv returns REPO_<NAME><suffix> (e.g. REPO_MINE_RANK=sql), falling
+// back to REPO<suffix> for every repo.
+func repoEnv(name, suffix, fallback string) string {
+	if v := os.Getenv("REPO_" + strings.ToUpper(name) + suffix); v != "" {
+		return v
+	}
+	return os.Getenv(fallback)
+}
+
+// newRepo creates the repo called name, wrapped in an LRU cache of
+// REPO_<NAME>_CACHE or REPO_CACHE entries if set.
+func newRepo[T any](dir string, name string, botID int64, keyring *helper.Keyring) (infoRepo[T], error) {
+	repo, err := openRepo[T](dir, name, botID, keyring)
+	if err != nil {
+		return nil, err
+	}
+	if n := repoEnv(name, "_CACHE", "REPO_CACHE"); n != "" {
+		size, err := strconv.Atoi(n)
+		if err != nil {
+			repo.Stop()
+			return nil, errors.New("repo " + name + ": invalid cache size " + n)
+		}
+		return helper.NewCachedRepo[T](repo, size), nil
+	}
+	return repo, nil
+}
+
+// openRepo creates the repo called name with the backend chosen by
+// REPO_<NAME> or REPO_TYPE, a FileRepo by default. A FileRepo encodes with
+// the codec of REPO_<NAME>_CODEC or REPO_CODEC (json, gob, msgpack),
+// compresses with REPO_<NAME>_COMPRESSION or REPO_COMPRESSION (none, gzip,
+// zstd), splits its files into REPO_<NAME>_SHARDS or REPO_SHARDS shards and
+// is encrypted if keyring is set. SQLRepo cannot encrypt, so it is refused
+// with a keyring; a MemRepo keeps nothing at rest to encrypt.
+func openRepo[T any](dir string, name string, botID int64, keyring *helper.Keyring) (infoRepo[T], error) {
+	t := repoEnv(name, "", "REPO_TYPE")
+	switch {
+	case strings.EqualFold(t, string(helper.SQL)):
+		if keyring != nil {
+			return nil, errors.New("repo " + name + ": the SQL backend cannot be encrypted, unset the keys or use the File backend")
+		}
+		return helper.OpenSQLRepo[T](dir, name, helper.WithBotID(botID))
+	case strings.EqualFold(t, string(helper.Memory)):
+		return helper.NewMemRepo[T](name), nil
+	}
+
+	opts := []helper.RepoOption{helper.WithBotID(botID)}
+	if c := repoEnv(name, "_CODEC", "REPO_CODEC"); c != "" {
+		codec, ok := helper.CodecByName(strings.ToLower(c))
+		if !ok {
+			return nil, errors.New("repo " + name + ": unknown codec " + c)
+		}
+		opts = append(opts, helper.WithCodec(codec))
+	}
+	if c := repoEnv(name, "_COMPRESSION", "REPO_COMPRESSION"); c != "" {
+		compression, ok := helper.CompressionByName(strings.ToLower(c))
+		if !ok {
+			return nil, errors.New("repo " + name + ": unknown compression " + c)
+		}
+		opts = append(opts, helper.WithCompression(compression))
+	}
+	if n := repoEnv(name, "_SHARDS", "REPO_SHARDS"); n != "" {
+		shards, err := strconv.Atoi(n)
+		if err != nil {
+			return nil, errors.New("repo " + name + ": invalid shards " + n)
+		}
+		opts = append(opts, helper.WithShards(shards))
+	}
+	if keyring != nil {
+		opts = append(opts, helper.WithKeyring(keyring))
+	}
+	return helper.OpenFileRepo[T](dir, name, opts...)
 }
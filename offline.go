@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+/*
+ocha_bot backup  [file]
+ocha_bot restore file
+*/
+
+// offline runs a maintenance command against the repos of one bot without
+// connecting to Telegram. With several bots configured, BOT_ID selects it.
+// The data directory lock keeps a running bot from using the repos meanwhile.
+func offline(cfg config, args []string) error {
+	id, err := offlineBot(cfg)
+	if err != nil {
+		return err
+	}
+	dir := cfg.Dir
+
+	switch args[0] {
+	case "backup":
+		r, err := openRepos(dir, id, cfg.keyring)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+		b := r.backup(id)
+		now := time.Now()
+		filename := b.FileName(now)
+		if len(args) > 1 {
+			filename = args[1]
+		}
+		// the archive holds every repo, so only its owner may read it
+		file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		archive, err := b.Export(file)
+		if err != nil {
+			return err
+		}
+		if err = file.Sync(); err != nil {
+			return err
+		}
+		log.Printf("Backup %d repos into %s success", len(archive.Repos), filename)
+	case "restore":
+		if len(args) < 2 {
+			return errors.New("usage: restore <file>")
+		}
+		file, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		records, err := restoreRepos(dir, id, cfg.keyring, file)
+		if err != nil {
+			return err
+		}
+		log.Printf("Restore %d records from %s success", records, args[1])
+	default:
+		return errors.New("unknown command " + args[0] + ", expected backup or restore")
+	}
+	return nil
+}
+
+func offlineBot(cfg config) (int64, error) {
+	if s := os.Getenv("BOT_ID"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, errors.New("BOT_ID is malformed")
+		}
+		for _, bc := range cfg.Bots {
+			if botID, _ := botID(bc.Token); botID == id {
+				return id, nil
+			}
+		}
+		return 0, errors.New("bot " + s + " is not configured")
+	}
+	if len(cfg.Bots) > 1 {
+		return 0, errors.New("several bots are configured, choose one with BOT_ID")
+	}
+	return botID(cfg.Bots[0].Token)
+}